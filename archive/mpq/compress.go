@@ -0,0 +1,44 @@
+package mpq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/compress/pkware"
+)
+
+// decompress decompresses the given file or sector data, as specified by the
+// block flags, and returns the decompressed data of the given size.
+func decompress(data []byte, flags uint32, size int) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case flags&flagImplode != 0:
+		r = pkware.NewReader(bytes.NewReader(data))
+	case flags&flagCompress != 0:
+		if len(data) == 0 {
+			return nil, errors.New("missing compression method")
+		}
+		method, data := data[0], data[1:]
+		switch method {
+		case compressPKWARE:
+			r = pkware.NewReader(bytes.NewReader(data))
+		case compressZlib:
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			r = zr
+		default:
+			return nil, errors.Errorf("support for compression method 0x%02X not yet implemented", method)
+		}
+	default:
+		return nil, errors.Errorf("invalid size of uncompressed data; expected %d, got %d", size, len(data))
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf, nil
+}
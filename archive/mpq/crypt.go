@@ -0,0 +1,105 @@
+package mpq
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Hash types of hashString.
+const (
+	// Index into the hash table.
+	hashTableIndex = 0
+	// First hash of file names, stored in the hash table.
+	hashNameA = 1
+	// Second hash of file names, stored in the hash table.
+	hashNameB = 2
+	// Encryption key of files and tables.
+	hashFileKey = 3
+)
+
+// Encryption keys of the hash and block tables.
+var (
+	hashTableKey  = hashString("(hash table)", hashFileKey)
+	blockTableKey = hashString("(block table)", hashFileKey)
+)
+
+// cryptTable is the table of pseudo-random values used by the hash function and
+// the encryption of MPQ archives.
+var cryptTable = initCryptTable()
+
+// initCryptTable initializes the table of pseudo-random values used by the hash
+// function and the encryption of MPQ archives.
+func initCryptTable() *[0x500]uint32 {
+	t := &[0x500]uint32{}
+	seed := uint32(0x00100001)
+	for i := 0; i < 0x100; i++ {
+		for j, idx := 0, i; j < 5; j, idx = j+1, idx+0x100 {
+			seed = (seed*125 + 3) % 0x2AAAAB
+			hi := (seed & 0xFFFF) << 16
+			seed = (seed*125 + 3) % 0x2AAAAB
+			lo := seed & 0xFFFF
+			t[idx] = hi | lo
+		}
+	}
+	return t
+}
+
+// hashString returns the hash of the given file name of the specified hash
+// type. File names are case-insensitive, and use backslash as path separator.
+func hashString(name string, hashType uint32) uint32 {
+	seed1, seed2 := uint32(0x7FED7FED), uint32(0xEEEEEEEE)
+	for _, c := range []byte(normName(name)) {
+		seed1 = cryptTable[hashType<<8+uint32(c)] ^ (seed1 + seed2)
+		seed2 = uint32(c) + seed1 + seed2 + seed2<<5 + 3
+	}
+	return seed1
+}
+
+// normName returns the normalized file name of the given path; using upper case
+// letters and backslash as path separator.
+func normName(name string) string {
+	return strings.ToUpper(strings.Replace(name, "/", `\`, -1))
+}
+
+// fileKey returns the encryption key of the given file name, as stored at the
+// given block offset and of the given file size. Only the base name of the
+// file is used, unless adjusted by the block offset (see flagFixKey).
+func fileKey(name string, offset, size uint32, fixKey bool) uint32 {
+	name = normName(name)
+	if pos := strings.LastIndex(name, `\`); pos != -1 {
+		name = name[pos+1:]
+	}
+	key := hashString(name, hashFileKey)
+	if fixKey {
+		key = (key + offset) ^ size
+	}
+	return key
+}
+
+// decrypt decrypts the given data in place using the provided key. Data is
+// decrypted in units of 32-bit little-endian words; trailing bytes are left
+// unchanged.
+func decrypt(data []byte, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+	for i := 0; i+4 <= len(data); i += 4 {
+		seed += cryptTable[0x400+key&0xFF]
+		v := binary.LittleEndian.Uint32(data[i:]) ^ (key + seed)
+		key = (^key<<0x15 + 0x11111111) | key>>0x0B
+		seed = v + seed + seed<<5 + 3
+		binary.LittleEndian.PutUint32(data[i:], v)
+	}
+}
+
+// encrypt encrypts the given data in place using the provided key. Data is
+// encrypted in units of 32-bit little-endian words; trailing bytes are left
+// unchanged.
+func encrypt(data []byte, key uint32) {
+	seed := uint32(0xEEEEEEEE)
+	for i := 0; i+4 <= len(data); i += 4 {
+		seed += cryptTable[0x400+key&0xFF]
+		v := binary.LittleEndian.Uint32(data[i:])
+		binary.LittleEndian.PutUint32(data[i:], v^(key+seed))
+		key = (^key<<0x15 + 0x11111111) | key>>0x0B
+		seed = v + seed + seed<<5 + 3
+	}
+}
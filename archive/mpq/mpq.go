@@ -0,0 +1,605 @@
+// Package mpq implements access to MPQ archives (e.g. "diabdat.mpq").
+//
+// An Archive provides the files of an MPQ archive through the fs.FS interface,
+// so that game assets may be read without first extracting the archive. A
+// Writer creates MPQ archives, e.g. to repack modified game assets.
+//
+// Below follows a pseudo-code description of the MPQ file format (version 1).
+//
+//    // An MPQ archive consists of a header, file data, a hash table and a
+//    // block table. The header is located at a 512-byte aligned offset of the
+//    // file, and offsets of the header are relative to the header.
+//    type MPQ struct {
+//       header     Header
+//       hashTable  [header.hashTableEntries]HashEntry  // encrypted
+//       blockTable [header.blockTableEntries]BlockEntry // encrypted
+//    }
+//
+//    type Header struct {
+//       magic             [4]byte // "MPQ\x1A"
+//       headerSize        uint32  // 32
+//       archiveSize       uint32
+//       formatVersion     uint16  // 0
+//       sectorSizeShift   uint16  // sectorSize = 512 << sectorSizeShift
+//       hashTableOffset   uint32
+//       blockTableOffset  uint32
+//       hashTableEntries  uint32  // power of two
+//       blockTableEntries uint32
+//    }
+//
+//    // A HashEntry maps from the hash of a file name to a block entry.
+//    type HashEntry struct {
+//       hashA      uint32
+//       hashB      uint32
+//       locale     uint16
+//       platform   uint16
+//       blockIndex uint32 // 0xFFFFFFFF: empty, 0xFFFFFFFE: deleted
+//    }
+//
+//    // A BlockEntry specifies the location and storage of a file.
+//    type BlockEntry struct {
+//       offset         uint32
+//       compressedSize uint32
+//       fileSize       uint32
+//       flags          uint32
+//    }
+//
+// Files are stored in sectors of sectorSize bytes each; the sectors of
+// compressed files are preceded by a table of sector offsets. Files and sector
+// offset tables may be encrypted, and each sector may be compressed.
+package mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Block entry flags.
+const (
+	// File is compressed using PKWARE DCL implode.
+	flagImplode = 0x00000100
+	// File is compressed; the compression method of each sector is specified
+	// by its first byte.
+	flagCompress = 0x00000200
+	// File is encrypted.
+	flagEncrypted = 0x00010000
+	// Encryption key of file is adjusted by block offset and file size.
+	flagFixKey = 0x00020000
+	// File is stored as a single unit, rather than in sectors.
+	flagSingleUnit = 0x01000000
+	// File is a deletion marker.
+	flagDeleteMarker = 0x02000000
+	// Sector offset table is followed by sector checksums.
+	flagSectorCRC = 0x04000000
+	// File exists.
+	flagExists = 0x80000000
+)
+
+// Compression methods of sectors of files with flagCompress.
+const (
+	compressZlib   = 0x02
+	compressPKWARE = 0x08
+)
+
+// Block indices of hash entries.
+const (
+	blockEmpty   = 0xFFFFFFFF
+	blockDeleted = 0xFFFFFFFE
+)
+
+// magic specifies the magic signature of MPQ archive headers.
+const magic = "MPQ\x1A"
+
+// Sizes in bytes.
+const (
+	headerSize = 32
+	entrySize  = 16
+)
+
+// maxSectorSizeShift specifies the maximum sector size shift of archives; 16 MB
+// sectors.
+const maxSectorSizeShift = 15
+
+// maxRatio specifies the maximum compression ratio of file data; the
+// theoretical maximum of deflate (1032:1), which exceeds that of PKWARE DCL
+// implode.
+const maxRatio = 1032
+
+// listFile specifies the name of the optional list file of MPQ archives, which
+// contains the names of the files stored in the archive.
+const listFile = "(listfile)"
+
+// header is the header of an MPQ archive.
+type header struct {
+	Magic             [4]byte
+	HeaderSize        uint32
+	ArchiveSize       uint32
+	FormatVersion     uint16
+	SectorSizeShift   uint16
+	HashTableOffset   uint32
+	BlockTableOffset  uint32
+	HashTableEntries  uint32
+	BlockTableEntries uint32
+}
+
+// hashEntry is an entry of the hash table.
+type hashEntry struct {
+	HashA      uint32
+	HashB      uint32
+	Locale     uint16
+	Platform   uint16
+	BlockIndex uint32
+}
+
+// blockEntry is an entry of the block table.
+type blockEntry struct {
+	Offset         uint32
+	CompressedSize uint32
+	FileSize       uint32
+	Flags          uint32
+}
+
+// An Archive is an MPQ archive opened for reading. Archive implements fs.FS,
+// with file names relative to the root of the archive using forward slashes as
+// path separator; e.g. "levels/l1data/l1.cel". File names are
+// case-insensitive.
+//
+// As MPQ archives store hashes of file names, rather than the names, listing
+// directories requires the names of files; which are read from the list file of
+// the archive if present, and may be added using AddNames.
+type Archive struct {
+	// Underlying reader of the archive.
+	r io.ReaderAt
+	// Underlying file of the archive if opened by Open; or nil.
+	f *os.File
+	// Offset of the archive header within the underlying reader.
+	base int64
+	// Size of the underlying reader in bytes.
+	size int64
+	// Sector size in bytes.
+	sectorSize int
+	// Hash table.
+	hashes []hashEntry
+	// Block table.
+	blocks []blockEntry
+	// Known file names, mapping from lower case name to name.
+	names map[string]string
+}
+
+// Open opens the given MPQ archive for reading.
+func Open(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.WithStack(err)
+	}
+	a, err := NewReader(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "unable to open MPQ archive %q", path)
+	}
+	a.f = f
+	return a, nil
+}
+
+// NewReader returns a new archive reading from r, which has the given size in
+// bytes.
+func NewReader(r io.ReaderAt, size int64) (*Archive, error) {
+	// Locate archive header.
+	var hdr header
+	base := int64(-1)
+	for off := int64(0); off+headerSize <= size; off += 512 {
+		var buf [headerSize]byte
+		if _, err := r.ReadAt(buf[:], off); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if string(buf[:4]) != magic {
+			continue
+		}
+		if err := binary.Read(bytes.NewReader(buf[:]), binary.LittleEndian, &hdr); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		base = off
+		break
+	}
+	if base == -1 {
+		return nil, errors.New("unable to locate MPQ header")
+	}
+	if hdr.FormatVersion != 0 {
+		return nil, errors.Errorf("support for MPQ format version %d not yet implemented", hdr.FormatVersion)
+	}
+	if hdr.SectorSizeShift > maxSectorSizeShift {
+		return nil, errors.Errorf("invalid sector size shift %d; expected <= %d", hdr.SectorSizeShift, maxSectorSizeShift)
+	}
+	n := hdr.HashTableEntries
+	if n == 0 || n&(n-1) != 0 {
+		return nil, errors.Errorf("invalid number of hash table entries %d; expected power of two", n)
+	}
+	if end := base + int64(hdr.HashTableOffset) + int64(n)*entrySize; end > size {
+		return nil, errors.Errorf("hash table extends beyond end of file (%d > %d)", end, size)
+	}
+	if end := base + int64(hdr.BlockTableOffset) + int64(hdr.BlockTableEntries)*entrySize; end > size {
+		return nil, errors.Errorf("block table extends beyond end of file (%d > %d)", end, size)
+	}
+	a := &Archive{
+		r:          r,
+		base:       base,
+		size:       size,
+		sectorSize: 512 << hdr.SectorSizeShift,
+		names:      make(map[string]string),
+	}
+
+	// Read hash table.
+	buf, err := a.readTable(hdr.HashTableOffset, hdr.HashTableEntries, hashTableKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read hash table")
+	}
+	a.hashes = make([]hashEntry, hdr.HashTableEntries)
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, a.hashes); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Read block table.
+	buf, err = a.readTable(hdr.BlockTableOffset, hdr.BlockTableEntries, blockTableKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read block table")
+	}
+	a.blocks = make([]blockEntry, hdr.BlockTableEntries)
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, a.blocks); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Read file names of the list file if present.
+	if data, err := a.ReadFile(listFile); err == nil {
+		a.AddNames(parseListFile(data))
+	}
+	return a, nil
+}
+
+// readTable reads and decrypts the hash or block table of the given offset and
+// number of entries.
+func (a *Archive) readTable(offset, n uint32, key uint32) ([]byte, error) {
+	buf := make([]byte, int(n)*entrySize)
+	if _, err := a.r.ReadAt(buf, a.base+int64(offset)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	decrypt(buf, key)
+	return buf, nil
+}
+
+// parseListFile returns the file names of the given list file, which are
+// separated by line breaks or semicolons.
+func parseListFile(data []byte) []string {
+	return strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == '\r' || r == '\n' || r == ';'
+	})
+}
+
+// Close closes the archive. The underlying reader is closed if the archive was
+// opened by Open.
+func (a *Archive) Close() error {
+	if a.f == nil {
+		return nil
+	}
+	if err := a.f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// AddNames adds the given file names to the known file names of the archive,
+// which are used to list directories. Names of files not present in the
+// archive are ignored. Both forward slashes and backslashes are accepted as
+// path separator.
+func (a *Archive) AddNames(names []string) {
+	for _, name := range names {
+		name = strings.Replace(strings.TrimSpace(name), `\`, "/", -1)
+		if name == "" || name == listFile {
+			continue
+		}
+		if _, ok := a.lookup(name); !ok {
+			continue
+		}
+		a.names[strings.ToLower(name)] = name
+	}
+}
+
+// Names returns the sorted known file names of the archive.
+func (a *Archive) Names() []string {
+	var names []string
+	for _, name := range a.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the block entry of the given file name.
+func (a *Archive) lookup(name string) (blockEntry, bool) {
+	n := uint32(len(a.hashes))
+	if n == 0 {
+		return blockEntry{}, false
+	}
+	start := hashString(name, hashTableIndex) & (n - 1)
+	hashA := hashString(name, hashNameA)
+	hashB := hashString(name, hashNameB)
+	for i := uint32(0); i < n; i++ {
+		h := a.hashes[(start+i)&(n-1)]
+		if h.BlockIndex == blockEmpty {
+			break
+		}
+		if h.BlockIndex == blockDeleted || h.HashA != hashA || h.HashB != hashB {
+			continue
+		}
+		if int(h.BlockIndex) >= len(a.blocks) {
+			return blockEntry{}, false
+		}
+		b := a.blocks[h.BlockIndex]
+		if b.Flags&flagExists == 0 || b.Flags&flagDeleteMarker != 0 {
+			return blockEntry{}, false
+		}
+		return b, true
+	}
+	return blockEntry{}, false
+}
+
+// Open opens the named file of the archive.
+func (a *Archive) Open(name string) (fs.File, error) {
+	if !validPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if _, ok := a.lookup(name); ok {
+		data, err := a.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		return &file{info: fileInfo{name: path.Base(name), size: int64(len(data))}, r: bytes.NewReader(data)}, nil
+	}
+	if entries, ok := a.readDir(name); ok {
+		return &dir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadFile reads the named file of the archive and returns its contents.
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	if !validPath(name) {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+	b, ok := a.lookup(name)
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	data, err := a.readFile(name, b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %q", name)
+	}
+	return data, nil
+}
+
+// validPath reports whether the given file name is valid for use with Open;
+// file names use forward slashes as path separator.
+func validPath(name string) bool {
+	return fs.ValidPath(name) && !strings.Contains(name, `\`)
+}
+
+// readFile reads the contents of the named file, stored in the given block.
+func (a *Archive) readFile(name string, b blockEntry) ([]byte, error) {
+	if end := a.base + int64(b.Offset) + int64(b.CompressedSize); end > a.size {
+		return nil, errors.Errorf("file data extends beyond end of archive (%d > %d)", end, a.size)
+	}
+	raw := make([]byte, b.CompressedSize)
+	if _, err := a.r.ReadAt(raw, a.base+int64(b.Offset)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Bound the file size by the size of the stored file data, before the file
+	// contents are allocated.
+	compressed := b.Flags&(flagImplode|flagCompress) != 0
+	maxSize := int64(b.CompressedSize)
+	if compressed {
+		maxSize *= maxRatio
+	}
+	if int64(b.FileSize) > maxSize {
+		return nil, errors.Errorf("invalid file size %d; expected <= %d for %d bytes of file data", b.FileSize, maxSize, b.CompressedSize)
+	}
+	var key uint32
+	encrypted := b.Flags&flagEncrypted != 0
+	if encrypted {
+		key = fileKey(name, b.Offset, b.FileSize, b.Flags&flagFixKey != 0)
+	}
+	fileSize := int(b.FileSize)
+
+	// Single unit files.
+	if b.Flags&flagSingleUnit != 0 {
+		if encrypted {
+			decrypt(raw, key)
+		}
+		if len(raw) < fileSize {
+			return decompress(raw, b.Flags, fileSize)
+		}
+		return raw[:fileSize], nil
+	}
+
+	// Files stored in sectors.
+	nsectors := (fileSize + a.sectorSize - 1) / a.sectorSize
+	offsets := make([]int, nsectors+1)
+	if compressed {
+		// Read sector offset table.
+		n := nsectors + 1
+		if b.Flags&flagSectorCRC != 0 {
+			n++
+		}
+		if len(raw) < 4*n {
+			return nil, errors.Errorf("invalid sector offset table size; expected >= %d, got %d", 4*n, len(raw))
+		}
+		table := make([]byte, 4*n)
+		copy(table, raw)
+		if encrypted {
+			decrypt(table, key-1)
+		}
+		for i := range offsets {
+			offsets[i] = int(binary.LittleEndian.Uint32(table[4*i:]))
+		}
+	} else {
+		for i := range offsets {
+			offsets[i] = i * a.sectorSize
+		}
+		offsets[nsectors] = fileSize
+	}
+	for i := 0; i < nsectors; i++ {
+		start, end := offsets[i], offsets[i+1]
+		if start < 0 || start > end || end > len(raw) {
+			return nil, errors.Errorf("invalid offsets of sector %d; expected 0 <= %d <= %d <= %d", i, start, end, len(raw))
+		}
+	}
+	data := make([]byte, 0, fileSize)
+	for i := 0; i < nsectors; i++ {
+		sector := raw[offsets[i]:offsets[i+1]]
+		if encrypted {
+			decrypt(sector, key+uint32(i))
+		}
+		size := a.sectorSize
+		if rem := fileSize - i*a.sectorSize; rem < size {
+			size = rem
+		}
+		if len(sector) < size {
+			buf, err := decompress(sector, b.Flags, size)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to decompress sector %d", i)
+			}
+			sector = buf
+		}
+		if len(sector) != size {
+			return nil, errors.Errorf("invalid size of sector %d; expected %d, got %d", i, size, len(sector))
+		}
+		data = append(data, sector...)
+	}
+	return data, nil
+}
+
+// readDir returns the entries of the named directory, as determined by the
+// known file names of the archive.
+func (a *Archive) readDir(name string) ([]fs.DirEntry, bool) {
+	prefix := ""
+	if name != "." {
+		prefix = strings.ToLower(name) + "/"
+	}
+	found := name == "."
+	m := make(map[string]fs.DirEntry)
+	for lower, orig := range a.names {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		found = true
+		rest := orig[len(prefix):]
+		if pos := strings.Index(rest, "/"); pos != -1 {
+			sub := rest[:pos]
+			m[strings.ToLower(sub)] = fileInfo{name: sub, dir: true}
+			continue
+		}
+		b, _ := a.lookup(orig)
+		m[strings.ToLower(rest)] = fileInfo{name: rest, size: int64(b.FileSize)}
+	}
+	if !found {
+		return nil, false
+	}
+	var entries []fs.DirEntry
+	for _, entry := range m {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, true
+}
+
+// fileInfo describes a file or directory of an archive. It implements both
+// fs.FileInfo and fs.DirEntry.
+type fileInfo struct {
+	// Base name.
+	name string
+	// File size in bytes.
+	size int64
+	// Directory.
+	dir bool
+}
+
+func (fi fileInfo) Name() string               { return fi.name }
+func (fi fileInfo) Size() int64                { return fi.size }
+func (fi fileInfo) ModTime() time.Time         { return time.Time{} }
+func (fi fileInfo) IsDir() bool                { return fi.dir }
+func (fi fileInfo) Sys() interface{}           { return nil }
+func (fi fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// file is a file of an archive opened for reading.
+type file struct {
+	info fileInfo
+	r    *bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *file) Close() error               { return nil }
+
+// Seek implements io.Seeker.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	return f.r.Seek(offset, whence)
+}
+
+// ReadAt implements io.ReaderAt.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	return f.r.ReadAt(p, off)
+}
+
+// dir is a directory of an archive opened for reading.
+type dir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	// Number of entries read by ReadDir.
+	pos int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.pos:]
+	if n <= 0 {
+		d.pos = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.pos += n
+	return rest[:n], nil
+}
@@ -0,0 +1,363 @@
+package mpq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+)
+
+func TestCrypt(t *testing.T) {
+	golden := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{name: "cryptTable[0]", got: cryptTable[0], want: 0x55C636E2},
+		{name: "hash table key", got: hashTableKey, want: 0xC3AF3770},
+		{name: "block table key", got: blockTableKey, want: 0xEC83B3A3},
+	}
+	for _, g := range golden {
+		if g.got != g.want {
+			t.Errorf("%s mismatch; expected 0x%08X, got 0x%08X", g.name, g.want, g.got)
+		}
+	}
+
+	want := []byte("The quick brown fox jumps over the lazy dog.")
+	buf := append([]byte(nil), want...)
+	encrypt(buf, 0x12345678)
+	if bytes.Equal(buf, want) {
+		t.Errorf("expected encrypted data to differ from plaintext")
+	}
+	decrypt(buf, 0x12345678)
+	if !bytes.Equal(buf, want) {
+		t.Errorf("data mismatch after encrypt and decrypt; expected %q, got %q", want, buf)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	// Data spanning several sectors.
+	long := bytes.Repeat([]byte("diablo "), 300)
+	files := []testFile{
+		{name: "(listfile)", data: []byte("levels\\l1data\\l1.til\r\nlevels\\l1data\\l1.min\r\nsingle.bin\r\nimplode.bin\r\n"), flags: flagExists},
+		{name: "levels/l1data/l1.til", data: long, flags: flagExists},
+		{name: "levels/l1data/l1.min", data: long, flags: flagExists | flagCompress | flagEncrypted | flagFixKey},
+		{name: "single.bin", data: []byte("single unit"), flags: flagExists | flagSingleUnit | flagEncrypted},
+		// Example of blast.c by Mark Adler.
+		{name: "implode.bin", data: []byte("AIAIAIAIAIAIA"), imploded: [][]byte{{0x00, 0x04, 0x82, 0x24, 0x25, 0x8F, 0x80, 0x7F}}, flags: flagExists | flagImplode},
+	}
+	buf := buildArchive(t, files)
+	a, err := NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		t.Fatalf("unable to open archive; %+v", err)
+	}
+	for _, f := range files[1:] {
+		got, err := fs.ReadFile(a, f.name)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %+v", f.name, err)
+			continue
+		}
+		if !bytes.Equal(got, f.data) {
+			t.Errorf("%q: file contents mismatch", f.name)
+		}
+	}
+	// File names are case-insensitive.
+	if _, err := a.ReadFile("LEVELS/L1DATA/L1.TIL"); err != nil {
+		t.Errorf("unable to read file using upper case name; %v", err)
+	}
+	if _, err := a.ReadFile("missing.bin"); err == nil {
+		t.Errorf("expected error for missing file, got nil")
+	}
+	if err := fstest.TestFS(a, "levels/l1data/l1.til", "levels/l1data/l1.min", "single.bin", "implode.bin"); err != nil {
+		t.Error(err)
+	}
+
+	// File sizes are bounded by the size of the stored file data.
+	for i := range a.blocks {
+		a.blocks[i].FileSize = 0xFFFFFFF0
+	}
+	for _, f := range files[1:] {
+		if _, err := a.ReadFile(f.name); err == nil {
+			t.Errorf("%q: expected error for invalid file size, got nil", f.name)
+		}
+	}
+
+	// Invalid sector size.
+	corrupt := append([]byte(nil), buf...)
+	binary.LittleEndian.PutUint16(corrupt[14:], 64)
+	if _, err := NewReader(bytes.NewReader(corrupt), int64(len(corrupt))); err == nil {
+		t.Errorf("expected error for invalid sector size shift, got nil")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"levels/l1data/l1.til":  {Data: bytes.Repeat([]byte("diablo "), 3000)},
+		"levels/l1data/l1.sol":  {Data: []byte{0x01, 0x02, 0x04, 0x08}},
+		"objects/empty.cel":     {Data: nil},
+		"ctrlpan/golddrop.cel":  {Data: []byte(strings.Repeat("\x00\xFF", 5000))},
+		"levels/towndata/a.dun": {Data: []byte{0x01, 0x00, 0x01, 0x00, 0x07, 0x00}},
+	}
+	var want []string
+	for name := range fsys {
+		want = append(want, name)
+	}
+	for _, compress := range []bool{false, true} {
+		mpqPath := filepath.Join(t.TempDir(), "test.mpq")
+		f, err := os.Create(mpqPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(f, compress)
+		if err != nil {
+			t.Fatalf("unable to create MPQ writer; %+v", err)
+		}
+		if err := w.AddFS(fsys); err != nil {
+			t.Fatalf("unable to add files; %+v", err)
+		}
+		if err := w.WriteFile("LEVELS/L1DATA/L1.TIL", nil); err == nil {
+			t.Errorf("expected error for duplicate file name, got nil")
+		}
+		if err := w.WriteFile("(LISTFILE)", nil); err == nil {
+			t.Errorf("expected error for list file name, got nil")
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unable to close MPQ writer; %+v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := Open(mpqPath)
+		if err != nil {
+			t.Fatalf("compress=%v: unable to open archive; %+v", compress, err)
+		}
+		for name, f := range fsys {
+			b, _ := a.lookup(name)
+			if compress && len(f.Data) > 0 && b.Flags&flagImplode == 0 {
+				t.Errorf("compress=%v: %q: expected implode flag", compress, name)
+			}
+			got, err := a.ReadFile(name)
+			if err != nil {
+				t.Errorf("compress=%v: %q: unable to read file; %+v", compress, name, err)
+				continue
+			}
+			if !bytes.Equal(got, f.Data) {
+				t.Errorf("compress=%v: %q: file contents mismatch", compress, name)
+			}
+		}
+		sort.Strings(want)
+		if got := a.Names(); !reflect.DeepEqual(got, want) {
+			t.Errorf("compress=%v: file names mismatch; expected %q, got %q", compress, want, got)
+		}
+		if err := fstest.TestFS(a, want...); err != nil {
+			t.Errorf("compress=%v: %v", compress, err)
+		}
+		a.Close()
+	}
+}
+
+func TestWriterCloseRetry(t *testing.T) {
+	data := []byte("diablo")
+	f, err := os.Create(filepath.Join(t.TempDir(), "test.mpq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fw := &failWriter{File: f}
+	w, err := NewWriter(fw, false)
+	if err != nil {
+		t.Fatalf("unable to create MPQ writer; %+v", err)
+	}
+	if err := w.WriteFile("a.bin", data); err != nil {
+		t.Fatalf("unable to write file; %+v", err)
+	}
+
+	// Failed Close leaves the writer open.
+	fw.fail = true
+	if err := w.Close(); err == nil {
+		t.Fatalf("expected error for failed write, got nil")
+	}
+	fw.fail = false
+	if err := w.WriteFile("b.bin", data); err != nil {
+		t.Fatalf("unable to write file after failed Close; %+v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unable to close MPQ writer; %+v", err)
+	}
+	if err := w.WriteFile("c.bin", data); err == nil {
+		t.Errorf("expected error for write to closed MPQ writer, got nil")
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewReader(f, fi.Size())
+	if err != nil {
+		t.Fatalf("unable to open archive; %+v", err)
+	}
+	for _, name := range []string{"a.bin", "b.bin"} {
+		got, err := a.ReadFile(name)
+		if err != nil {
+			t.Errorf("%q: unable to read file; %+v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%q: file contents mismatch", name)
+		}
+	}
+	if got, want := a.Names(), []string{"a.bin", "b.bin"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("file names mismatch; expected %q, got %q", want, got)
+	}
+}
+
+// A failWriter is a file which fails to write when fail is set.
+type failWriter struct {
+	*os.File
+	fail bool
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("write failed")
+	}
+	return w.File.Write(p)
+}
+
+// A testFile specifies a file of a test archive.
+type testFile struct {
+	name string
+	data []byte
+	// Sectors compressed using PKWARE DCL implode; used if flagImplode is set.
+	imploded [][]byte
+	flags    uint32
+}
+
+// buildArchive returns an MPQ archive of the given files, with a sector size of
+// 512 bytes.
+func buildArchive(t *testing.T, files []testFile) []byte {
+	const (
+		sectorSize = 512
+		nhashes    = 16
+	)
+	buf := make([]byte, headerSize)
+	var blocks []blockEntry
+	hashes := make([]hashEntry, nhashes)
+	for i := range hashes {
+		hashes[i] = hashEntry{HashA: blockEmpty, HashB: blockEmpty, Locale: 0xFFFF, Platform: 0xFFFF, BlockIndex: blockEmpty}
+	}
+	for i, f := range files {
+		offset := uint32(len(buf))
+		var key uint32
+		if f.flags&flagEncrypted != 0 {
+			key = fileKey(f.name, offset, uint32(len(f.data)), f.flags&flagFixKey != 0)
+		}
+		var stored []byte
+		switch {
+		case f.flags&flagSingleUnit != 0:
+			stored = append(stored, f.data...)
+			if f.flags&flagEncrypted != 0 {
+				encrypt(stored, key)
+			}
+		default:
+			var sectors [][]byte
+			for start := 0; start < len(f.data); start += sectorSize {
+				end := start + sectorSize
+				if end > len(f.data) {
+					end = len(f.data)
+				}
+				sector := append([]byte(nil), f.data[start:end]...)
+				if f.flags&flagImplode != 0 {
+					sector = append([]byte(nil), f.imploded[len(sectors)]...)
+				}
+				if f.flags&flagCompress != 0 {
+					zbuf := &bytes.Buffer{}
+					zbuf.WriteByte(compressZlib)
+					zw := zlib.NewWriter(zbuf)
+					zw.Write(sector)
+					zw.Close()
+					if zbuf.Len() < len(sector) {
+						sector = zbuf.Bytes()
+					}
+				}
+				if f.flags&flagEncrypted != 0 {
+					encrypt(sector, key+uint32(len(sectors)))
+				}
+				sectors = append(sectors, sector)
+			}
+			if f.flags&(flagImplode|flagCompress) != 0 {
+				table := make([]byte, 4*(len(sectors)+1))
+				off := len(table)
+				for j, sector := range sectors {
+					binary.LittleEndian.PutUint32(table[4*j:], uint32(off))
+					off += len(sector)
+				}
+				binary.LittleEndian.PutUint32(table[4*len(sectors):], uint32(off))
+				if f.flags&flagEncrypted != 0 {
+					encrypt(table, key-1)
+				}
+				stored = append(stored, table...)
+			}
+			for _, sector := range sectors {
+				stored = append(stored, sector...)
+			}
+		}
+		buf = append(buf, stored...)
+		blocks = append(blocks, blockEntry{
+			Offset:         offset,
+			CompressedSize: uint32(len(stored)),
+			FileSize:       uint32(len(f.data)),
+			Flags:          f.flags,
+		})
+		idx := hashString(f.name, hashTableIndex) % nhashes
+		for hashes[idx].BlockIndex != blockEmpty {
+			idx = (idx + 1) % nhashes
+		}
+		hashes[idx] = hashEntry{
+			HashA:      hashString(f.name, hashNameA),
+			HashB:      hashString(f.name, hashNameB),
+			BlockIndex: uint32(i),
+		}
+	}
+	hashTableOffset := uint32(len(buf))
+	buf = append(buf, encodeTable(t, hashes, hashTableKey)...)
+	blockTableOffset := uint32(len(buf))
+	buf = append(buf, encodeTable(t, blocks, blockTableKey)...)
+	hdr := header{
+		HeaderSize:        headerSize,
+		ArchiveSize:       uint32(len(buf)),
+		SectorSizeShift:   0,
+		HashTableOffset:   hashTableOffset,
+		BlockTableOffset:  blockTableOffset,
+		HashTableEntries:  nhashes,
+		BlockTableEntries: uint32(len(blocks)),
+	}
+	copy(hdr.Magic[:], magic)
+	hbuf := &bytes.Buffer{}
+	if err := binary.Write(hbuf, binary.LittleEndian, hdr); err != nil {
+		t.Fatal(err)
+	}
+	copy(buf, hbuf.Bytes())
+	return buf
+}
+
+// encodeTable encodes and encrypts the given hash or block table.
+func encodeTable(t *testing.T, table interface{}, key uint32) []byte {
+	buf := &bytes.Buffer{}
+	if err := binary.Write(buf, binary.LittleEndian, table); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	encrypt(data, key)
+	return data
+}
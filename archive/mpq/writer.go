@@ -0,0 +1,294 @@
+package mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/compress/pkware"
+)
+
+// sectorSizeShift specifies the sector size of archives created by Writer;
+// 4096 bytes, as used by "diabdat.mpq".
+const sectorSizeShift = 3
+
+// A Writer creates an MPQ archive (version 1), as supported by the original
+// game. Files are stored unencrypted, and the file names are stored in the list
+// file of the archive. The hash and block tables are encrypted using the
+// standard keys.
+type Writer struct {
+	// Underlying writer of the archive.
+	w io.WriteSeeker
+	// Offset of the archive header within the underlying writer.
+	base int64
+	// Offset of the next file relative to the archive header.
+	offset uint32
+	// Sector size in bytes.
+	sectorSize int
+	// Compress the sectors of files using PKWARE DCL implode.
+	compress bool
+	// Names of the files added, using forward slashes as path separator.
+	names []string
+	// Block table; one entry per file added.
+	blocks []blockEntry
+	// Known file names, mapping from normalized name to name.
+	added map[string]string
+	// Writer closed.
+	closed bool
+}
+
+// NewWriter returns a new writer which creates an MPQ archive at the current
+// position of w. The sectors of files are compressed using PKWARE DCL implode
+// if compress is set. The list file, hash table and block table are written
+// on Close.
+func NewWriter(w io.WriteSeeker, compress bool) (*Writer, error) {
+	base, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Reserve space for the archive header.
+	if _, err := w.Write(make([]byte, headerSize)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aw := &Writer{
+		w:          w,
+		base:       base,
+		offset:     headerSize,
+		sectorSize: 512 << sectorSizeShift,
+		compress:   compress,
+		added:      make(map[string]string),
+	}
+	return aw, nil
+}
+
+// WriteFile adds the named file with the given contents to the archive. File
+// names use forward slashes as path separator, and are case-insensitive.
+func (aw *Writer) WriteFile(name string, data []byte) error {
+	if aw.closed {
+		return errors.New("write to closed MPQ writer")
+	}
+	if !validPath(name) || name == "." {
+		return errors.Errorf("invalid file name %q", name)
+	}
+	key := normName(name)
+	if key == normName(listFile) {
+		return errors.Errorf("invalid file name %q; the list file is written on Close", name)
+	}
+	if prev, ok := aw.added[key]; ok {
+		return errors.Errorf("file %q already added as %q", name, prev)
+	}
+	if err := aw.writeFile(data); err != nil {
+		return errors.Wrapf(err, "unable to write %q", name)
+	}
+	aw.added[key] = name
+	aw.names = append(aw.names, name)
+	return nil
+}
+
+// AddFS adds the regular files of fsys to the archive, with file names relative
+// to the root of fsys.
+func (aw *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !d.Type().IsRegular() || normName(name) == normName(listFile) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return aw.WriteFile(name, data)
+	})
+}
+
+// writeFile writes the given file contents at the current offset of the
+// archive, and records its block entry.
+func (aw *Writer) writeFile(data []byte) error {
+	b := blockEntry{
+		Offset:   aw.offset,
+		FileSize: uint32(len(data)),
+		Flags:    flagExists,
+	}
+	stored := data
+	if aw.compress && len(data) > 0 {
+		var err error
+		stored, err = aw.compressSectors(data)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		b.Flags |= flagImplode
+	}
+	if uint64(aw.offset)+uint64(len(stored)) > 1<<32-1 {
+		return errors.New("archive exceeds 4 GB")
+	}
+	if _, err := aw.w.Write(stored); err != nil {
+		return errors.WithStack(err)
+	}
+	b.CompressedSize = uint32(len(stored))
+	aw.offset += b.CompressedSize
+	aw.blocks = append(aw.blocks, b)
+	return nil
+}
+
+// compressSectors compresses the sectors of the given file contents, and
+// returns the sector offset table followed by the sectors. Sectors which do
+// not shrink when compressed are stored uncompressed.
+func (aw *Writer) compressSectors(data []byte) ([]byte, error) {
+	nsectors := (len(data) + aw.sectorSize - 1) / aw.sectorSize
+	table := make([]byte, 4*(nsectors+1))
+	buf := &bytes.Buffer{}
+	buf.Write(table)
+	for i := 0; i < nsectors; i++ {
+		binary.LittleEndian.PutUint32(table[4*i:], uint32(buf.Len()))
+		start := i * aw.sectorSize
+		end := start + aw.sectorSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sector := data[start:end]
+		compressed, err := implode(sector)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(compressed) < len(sector) {
+			sector = compressed
+		}
+		buf.Write(sector)
+	}
+	binary.LittleEndian.PutUint32(table[4*nsectors:], uint32(buf.Len()))
+	stored := buf.Bytes()
+	copy(stored, table)
+	return stored, nil
+}
+
+// implode compresses the given sector using PKWARE DCL implode, with the
+// dictionary size chosen by the sector size as done by Storm.
+func implode(sector []byte) ([]byte, error) {
+	dictSize := 4096
+	switch {
+	case len(sector) < 0x600:
+		dictSize = 1024
+	case len(sector) < 0xC00:
+		dictSize = 2048
+	}
+	buf := &bytes.Buffer{}
+	w, err := pkware.NewWriter(buf, pkware.Binary, dictSize)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if _, err := w.Write(sector); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Close writes the list file, hash table, block table and header of the
+// archive. It does not close the underlying writer. If Close fails, the writer
+// remains open and Close may be retried.
+func (aw *Writer) Close() (err error) {
+	if aw.closed {
+		return nil
+	}
+	// Discard the list file and tables on failure, so that Close may be
+	// retried.
+	offset, nblocks := aw.offset, len(aw.blocks)
+	defer func() {
+		if err == nil {
+			aw.closed = true
+			return
+		}
+		aw.offset = offset
+		aw.blocks = aw.blocks[:nblocks]
+		if _, serr := aw.w.Seek(aw.base+int64(offset), io.SeekStart); serr != nil {
+			err = errors.Wrapf(err, "unable to rewind MPQ writer (%v)", serr)
+		}
+	}()
+
+	// Write list file, using backslash as path separator.
+	names := append(aw.names, listFile)
+	list := &bytes.Buffer{}
+	for _, name := range aw.names {
+		list.WriteString(strings.Replace(name, "/", `\`, -1))
+		list.WriteString("\r\n")
+	}
+	if err := aw.writeFile(list.Bytes()); err != nil {
+		return errors.Wrap(err, "unable to write list file")
+	}
+
+	// Build hash table, with at least one empty entry per two files.
+	nhashes := 16
+	for nhashes < 2*len(names) {
+		nhashes *= 2
+	}
+	hashes := make([]hashEntry, nhashes)
+	for i := range hashes {
+		hashes[i] = hashEntry{HashA: blockEmpty, HashB: blockEmpty, Locale: 0xFFFF, Platform: 0xFFFF, BlockIndex: blockEmpty}
+	}
+	mask := uint32(nhashes - 1)
+	for i, name := range names {
+		idx := hashString(name, hashTableIndex) & mask
+		for hashes[idx].BlockIndex != blockEmpty {
+			idx = (idx + 1) & mask
+		}
+		hashes[idx] = hashEntry{
+			HashA:      hashString(name, hashNameA),
+			HashB:      hashString(name, hashNameB),
+			BlockIndex: uint32(i),
+		}
+	}
+
+	// Write hash and block tables.
+	hdr := header{
+		HeaderSize:        headerSize,
+		SectorSizeShift:   sectorSizeShift,
+		HashTableEntries:  uint32(nhashes),
+		BlockTableEntries: uint32(len(aw.blocks)),
+	}
+	copy(hdr.Magic[:], magic)
+	tables := []struct {
+		offset *uint32
+		table  interface{}
+		key    uint32
+	}{
+		{offset: &hdr.HashTableOffset, table: hashes, key: hashTableKey},
+		{offset: &hdr.BlockTableOffset, table: aw.blocks, key: blockTableKey},
+	}
+	for _, t := range tables {
+		buf := &bytes.Buffer{}
+		if err := binary.Write(buf, binary.LittleEndian, t.table); err != nil {
+			return errors.WithStack(err)
+		}
+		data := buf.Bytes()
+		encrypt(data, t.key)
+		if _, err := aw.w.Write(data); err != nil {
+			return errors.WithStack(err)
+		}
+		*t.offset = aw.offset
+		aw.offset += uint32(len(data))
+	}
+	hdr.ArchiveSize = aw.offset
+
+	// Write header.
+	end, err := aw.w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := aw.w.Seek(aw.base, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := binary.Write(aw.w, binary.LittleEndian, hdr); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := aw.w.Seek(end, io.SeekStart); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package assets provides a catalogue of the files contained within
+// "diabdat.mpq".
+//
+// Each asset is identified by its path relative to "diabdat.mpq", and records
+// the file kind, the game subsystem owning the file, and the package of this
+// repository decoding the file (if any).
+package assets
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/image/cel/monsters"
+)
+
+// A Kind specifies the file format of an asset.
+type Kind uint8
+
+// File formats.
+const (
+	// CEL image.
+	CEL Kind = iota + 1
+	// CL2 image.
+	CL2
+	// Dungeon piece definitions.
+	MIN
+	// Tile definitions.
+	TIL
+	// Dungeon piece properties.
+	SOL
+	// Automap tile shapes.
+	AMP
+	// Dungeon level.
+	DUN
+	// Palette.
+	PAL
+	// Colour transition table.
+	TRN
+	// Audio; only the game music is catalogued so far.
+	WAV
+	// Video.
+	SMK
+	// PCX image.
+	PCX
+)
+
+// kindExts maps from file kind to file extension.
+var kindExts = map[Kind]string{
+	CEL: ".cel",
+	CL2: ".cl2",
+	MIN: ".min",
+	TIL: ".til",
+	SOL: ".sol",
+	AMP: ".amp",
+	DUN: ".dun",
+	PAL: ".pal",
+	TRN: ".trn",
+	WAV: ".wav",
+	SMK: ".smk",
+	PCX: ".pcx",
+}
+
+// String returns the upper-case file extension of the file kind; e.g. "CEL".
+func (kind Kind) String() string {
+	if ext, ok := kindExts[kind]; ok {
+		return strings.ToUpper(ext[1:])
+	}
+	return fmt.Sprintf("Kind(%d)", uint8(kind))
+}
+
+// decoders maps from file kind to the import path of the decoder package.
+var decoders = map[Kind]string{
+	CEL: "github.com/sanctuary/formats/image/cel",
+	CL2: "github.com/sanctuary/formats/image/cel",
+	MIN: "github.com/sanctuary/formats/level/min",
+	TIL: "github.com/sanctuary/formats/level/til",
+	SOL: "github.com/sanctuary/formats/level/sol",
+	AMP: "github.com/sanctuary/formats/level/amp",
+	DUN: "github.com/sanctuary/formats/level/dun",
+	PAL: "github.com/sanctuary/formats/image/cel",
+	TRN: "github.com/sanctuary/formats/image/cel",
+}
+
+// subsystems maps from top-level directory to the owning game subsystem.
+var subsystems = map[string]string{
+	"ctrlpan":  "control panel",
+	"data":     "user interface",
+	"gendata":  "cutscenes",
+	"items":    "items",
+	"levels":   "levels",
+	"missiles": "missiles",
+	"monsters": "monsters",
+	"music":    "music",
+	"objects":  "objects",
+	"plrgfx":   "players",
+	"towners":  "towners",
+	"ui_art":   "menus",
+}
+
+// An Asset specifies a file contained within "diabdat.mpq".
+type Asset struct {
+	// Path relative to "diabdat.mpq"; e.g. "levels/l1data/l1.min".
+	RelPath string
+	// File format.
+	Kind Kind
+	// Game subsystem owning the file; e.g. "levels".
+	Subsystem string
+	// Import path of the package decoding the file; or empty if not supported.
+	Decoder string
+}
+
+// all lists every known asset, sorted by relative path.
+var all []Asset
+
+func init() {
+	m := make(map[string]bool)
+	for _, relPath := range config.RelPaths {
+		m[relPath] = true
+		conf, err := config.Get(path.Base(relPath))
+		if err != nil {
+			panic(fmt.Errorf("assets: unable to locate CEL config of %q; %v", relPath, err))
+		}
+		for _, relPalPath := range conf.Pals {
+			m[relPalPath] = true
+		}
+	}
+	for _, relTrnPath := range monsters.Trns() {
+		m[relTrnPath] = true
+	}
+	for _, relPaths := range [][]string{levelPaths, dunPaths, palPaths, wavPaths, smkPaths, pcxPaths} {
+		for _, relPath := range relPaths {
+			m[relPath] = true
+		}
+	}
+	for relPath := range m {
+		asset, err := newAsset(relPath)
+		if err != nil {
+			panic(fmt.Errorf("assets: %v", err))
+		}
+		all = append(all, asset)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].RelPath < all[j].RelPath
+	})
+}
+
+// newAsset returns the asset of the given relative path, determining the file
+// kind from its extension and the subsystem from its top-level directory.
+func newAsset(relPath string) (Asset, error) {
+	asset := Asset{RelPath: relPath}
+	ext := path.Ext(relPath)
+	for kind, kindExt := range kindExts {
+		if kindExt == ext {
+			asset.Kind = kind
+			break
+		}
+	}
+	if asset.Kind == 0 {
+		return asset, errors.Errorf("unknown file extension of %q", relPath)
+	}
+	dir := relPath
+	if i := strings.IndexByte(relPath, '/'); i != -1 {
+		dir = relPath[:i]
+	}
+	subsystem, ok := subsystems[dir]
+	if !ok {
+		return asset, errors.Errorf("unknown subsystem of %q", relPath)
+	}
+	asset.Subsystem = subsystem
+	asset.Decoder = decoders[asset.Kind]
+	return asset, nil
+}
+
+// All returns every known asset, sorted by relative path.
+func All() []Asset {
+	return append([]Asset(nil), all...)
+}
+
+// ByKind returns the known assets of the given file kinds, sorted by relative
+// path.
+func ByKind(kinds ...Kind) []Asset {
+	var assets []Asset
+	for _, asset := range all {
+		for _, kind := range kinds {
+			if asset.Kind == kind {
+				assets = append(assets, asset)
+				break
+			}
+		}
+	}
+	return assets
+}
+
+// RelPaths returns the relative paths of the known assets of the given file
+// kinds, sorted by relative path.
+func RelPaths(kinds ...Kind) []string {
+	var relPaths []string
+	for _, asset := range ByKind(kinds...) {
+		relPaths = append(relPaths, asset.RelPath)
+	}
+	return relPaths
+}
+
+// Get returns the asset of the given path, relative to "diabdat.mpq".
+func Get(relPath string) (Asset, error) {
+	i := sort.Search(len(all), func(i int) bool {
+		return all[i].RelPath >= relPath
+	})
+	if i < len(all) && all[i].RelPath == relPath {
+		return all[i], nil
+	}
+	return Asset{}, errors.Errorf("unable to locate asset %q", relPath)
+}
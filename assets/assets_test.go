@@ -0,0 +1,42 @@
+package assets_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/formats/assets"
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+func TestByKind(t *testing.T) {
+	if got, want := len(assets.ByKind(assets.CEL, assets.CL2)), len(config.RelPaths); got != want {
+		t.Errorf("CEL and CL2 asset count mismatch; expected %d, got %d", want, got)
+	}
+	golden := []struct {
+		relPath   string
+		kind      assets.Kind
+		subsystem string
+		decoder   string
+	}{
+		{relPath: "levels/l1data/l1.min", kind: assets.MIN, subsystem: "levels", decoder: "github.com/sanctuary/formats/level/min"},
+		{relPath: "levels/towndata/town.pal", kind: assets.PAL, subsystem: "levels", decoder: "github.com/sanctuary/formats/image/cel"},
+		{relPath: "monsters/falspear/salam.trn", kind: assets.TRN, subsystem: "monsters", decoder: "github.com/sanctuary/formats/image/cel"},
+		{relPath: "plrgfx/warrior/wla/wlaas.cl2", kind: assets.CL2, subsystem: "players", decoder: "github.com/sanctuary/formats/image/cel"},
+		{relPath: "gendata/diablo1.smk", kind: assets.SMK, subsystem: "cutscenes", decoder: ""},
+	}
+	for _, g := range golden {
+		asset, err := assets.Get(g.relPath)
+		if err != nil {
+			t.Errorf("%q: unable to locate asset; %v", g.relPath, err)
+			continue
+		}
+		if asset.Kind != g.kind {
+			t.Errorf("%q: kind mismatch; expected %v, got %v", g.relPath, g.kind, asset.Kind)
+		}
+		if asset.Subsystem != g.subsystem {
+			t.Errorf("%q: subsystem mismatch; expected %q, got %q", g.relPath, g.subsystem, asset.Subsystem)
+		}
+		if asset.Decoder != g.decoder {
+			t.Errorf("%q: decoder mismatch; expected %q, got %q", g.relPath, g.decoder, asset.Decoder)
+		}
+	}
+}
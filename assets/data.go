@@ -0,0 +1,125 @@
+package assets
+
+// levelPaths lists the dungeon piece, tile, dungeon piece property and automap
+// files of each level type.
+var levelPaths = []string{
+	"levels/l1data/l1.amp",
+	"levels/l1data/l1.min",
+	"levels/l1data/l1.sol",
+	"levels/l1data/l1.til",
+	"levels/l2data/l2.amp",
+	"levels/l2data/l2.min",
+	"levels/l2data/l2.sol",
+	"levels/l2data/l2.til",
+	"levels/l3data/l3.amp",
+	"levels/l3data/l3.min",
+	"levels/l3data/l3.sol",
+	"levels/l3data/l3.til",
+	"levels/l4data/l4.amp",
+	"levels/l4data/l4.min",
+	"levels/l4data/l4.sol",
+	"levels/l4data/l4.til",
+	"levels/towndata/town.min",
+	"levels/towndata/town.sol",
+	"levels/towndata/town.til",
+}
+
+// dunPaths lists the DUN files of quest levels, set pieces and town sectors.
+var dunPaths = []string{
+	"levels/l1data/banner1.dun",
+	"levels/l1data/banner2.dun",
+	"levels/l1data/lv1mazea.dun",
+	"levels/l1data/lv1mazeb.dun",
+	"levels/l1data/rnd6.dun",
+	"levels/l1data/skngdo.dun",
+	"levels/l1data/sklkng.dun",
+	"levels/l1data/sklkng1.dun",
+	"levels/l1data/sklkng2.dun",
+	"levels/l1data/vile1.dun",
+	"levels/l1data/vile2.dun",
+	"levels/l2data/blind1.dun",
+	"levels/l2data/blind2.dun",
+	"levels/l2data/blood1.dun",
+	"levels/l2data/blood2.dun",
+	"levels/l2data/blood3.dun",
+	"levels/l2data/bonecha1.dun",
+	"levels/l2data/bonecha2.dun",
+	"levels/l2data/bonestr1.dun",
+	"levels/l2data/bonestr2.dun",
+	"levels/l3data/anvil.dun",
+	"levels/l3data/foulwatr.dun",
+	"levels/l4data/diab1.dun",
+	"levels/l4data/diab2a.dun",
+	"levels/l4data/diab2b.dun",
+	"levels/l4data/diab3a.dun",
+	"levels/l4data/diab3b.dun",
+	"levels/l4data/diab4a.dun",
+	"levels/l4data/diab4b.dun",
+	"levels/l4data/warlord.dun",
+	"levels/l4data/warlord2.dun",
+	"levels/towndata/sector1s.dun",
+	"levels/towndata/sector2s.dun",
+	"levels/towndata/sector3s.dun",
+	"levels/towndata/sector4s.dun",
+}
+
+// palPaths lists the PAL files not referenced by any CEL config, as they are
+// identical to other palettes.
+var palPaths = []string{
+	"levels/l1data/l1.pal",
+	"levels/l2data/l2.pal",
+	"levels/l3data/l3.pal",
+}
+
+// wavPaths lists the WAV files of the game music.
+//
+// TODO: Catalogue the sound effects of the "sfx/" directory, and the monster
+// sounds of the "monsters/" directory. Only the music is catalogued so far.
+var wavPaths = []string{
+	"music/dintro.wav",
+	"music/dlvla.wav",
+	"music/dlvlb.wav",
+	"music/dlvlc.wav",
+	"music/dlvld.wav",
+	"music/dtowne.wav",
+}
+
+// smkPaths lists the SMK files of the cinematics.
+var smkPaths = []string{
+	"gendata/diabend.smk",
+	"gendata/diablo1.smk",
+	"gendata/diabvic1.smk",
+	"gendata/diabvic2.smk",
+	"gendata/diabvic3.smk",
+	"gendata/logo.smk",
+}
+
+// pcxPaths lists the PCX files of the menus.
+var pcxPaths = []string{
+	"ui_art/black.pcx",
+	"ui_art/but_lrg.pcx",
+	"ui_art/but_med.pcx",
+	"ui_art/but_sml.pcx",
+	"ui_art/but_xsm.pcx",
+	"ui_art/credits.pcx",
+	"ui_art/cursor.pcx",
+	"ui_art/focus.pcx",
+	"ui_art/focus16.pcx",
+	"ui_art/focus42.pcx",
+	"ui_art/font16.pcx",
+	"ui_art/font24.pcx",
+	"ui_art/font30.pcx",
+	"ui_art/font42.pcx",
+	"ui_art/heros.pcx",
+	"ui_art/logo.pcx",
+	"ui_art/mainmenu.pcx",
+	"ui_art/sb_arrow.pcx",
+	"ui_art/sb_bg.pcx",
+	"ui_art/sb_thumb.pcx",
+	"ui_art/selconn.pcx",
+	"ui_art/selgame.pcx",
+	"ui_art/selhero.pcx",
+	"ui_art/smlogo.pcx",
+	"ui_art/spinner.pcx",
+	"ui_art/title.pcx",
+}
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"image/color"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -17,8 +18,11 @@ import (
 	"github.com/mewkiz/pkg/pathutil"
 	"github.com/mewkiz/pkg/term"
 	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/assets"
 	"github.com/sanctuary/formats/image/cel"
 	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/internal/assetfs"
+	"github.com/sanctuary/formats/repair"
 )
 
 // dbg represents a logger with the "cel_dump:" prefix, which logs debug
@@ -46,11 +50,18 @@ func main() {
 	var (
 		// mpqDir specifies the path to an extracted "diabdat.mpq".
 		mpqDir string
+		// mpqPath specifies the path to "diabdat.mpq".
+		mpqPath string
 		// all specifies whether to dump all CEL images.
 		all bool
+		// fix specifies whether to repair the known broken files of
+		// "diabdat.mpq" in memory.
+		fix bool
 	)
 	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `path to extracted "diabdat.mpq"`)
+	flag.StringVar(&mpqPath, "mpq", "", `path to "diabdat.mpq" (takes precedence over -mpqdir)`)
 	flag.BoolVar(&all, "a", false, "dump all CEL images")
+	flag.BoolVar(&fix, "fix", false, `repair known broken files of "diabdat.mpq" in memory`)
 	flag.Usage = usage
 	flag.Parse()
 	if !all && flag.NArg() == 0 {
@@ -61,14 +72,21 @@ func main() {
 	// Determine relative CEL paths.
 	var relCelPaths []string
 	if all {
-		for _, relCelPath := range config.RelPaths {
-			relCelPaths = append(relCelPaths, relCelPath)
-		}
+		relCelPaths = assets.RelPaths(assets.CEL, assets.CL2)
 	} else {
 		relCelPaths = flag.Args()
 	}
 	sort.Strings(relCelPaths)
 
+	// Open game assets.
+	fsys, err := assetfs.Open(mpqPath, mpqDir)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if fix {
+		fsys = repair.FS(fsys)
+	}
+
 	// Parse CEL and CL2 files.
 	for _, relCelPath := range relCelPaths {
 		celName := filepath.Base(relCelPath)
@@ -77,11 +95,11 @@ func main() {
 			log.Fatalf("%+v", err)
 		}
 		if conf.Nimgs > 0 {
-			if err := dumpArchive(mpqDir, relCelPath, conf); err != nil {
+			if err := dumpArchive(fsys, relCelPath, conf); err != nil {
 				log.Fatalf("%+v", err)
 			}
 		} else {
-			if err := dumpCel(mpqDir, relCelPath, conf); err != nil {
+			if err := dumpCel(fsys, relCelPath, conf); err != nil {
 				log.Fatalf("%+v", err)
 			}
 		}
@@ -89,7 +107,7 @@ func main() {
 }
 
 // dumpArchive converts the given CEL archive to a set of PNG images.
-func dumpArchive(mpqDir, relCelPath string, conf *config.Config) error {
+func dumpArchive(fsys fs.FS, relCelPath string, conf *config.Config) error {
 	dbg.Printf("Extracting %q.", relCelPath)
 	// TODO: Remove temporary hack when the config package containing accurate
 	// palette descriptions.
@@ -98,8 +116,7 @@ func dumpArchive(mpqDir, relCelPath string, conf *config.Config) error {
 	}
 	for _, relPalPath := range conf.Pals {
 		// Parse PAL file.
-		palPath := filepath.Join(mpqDir, relPalPath)
-		pal, err := cel.ParsePal(palPath)
+		pal, err := cel.ParsePalFS(fsys, relPalPath)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -113,16 +130,14 @@ func dumpArchive(mpqDir, relCelPath string, conf *config.Config) error {
 		dstDir := filepath.Join("_dump_", celDir, palDir)
 
 		// Dump CEL image.
-		celPath := filepath.Join(mpqDir, relCelPath)
-		if err := dumpArchiveWithPal(dstDir, celPath, pal); err != nil {
+		if err := dumpArchiveWithPal(dstDir, fsys, relCelPath, pal); err != nil {
 			return errors.WithStack(err)
 		}
 
 		// Dump CEL image with colour transitions.
 		for _, relTrnPath := range conf.Trns {
 			// Parse TRN file.
-			trnPath := filepath.Join(mpqDir, relTrnPath)
-			trn, err := cel.ParseTrn(trnPath)
+			trn, err := cel.ParseTrnFS(fsys, relTrnPath)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -133,7 +148,7 @@ func dumpArchive(mpqDir, relCelPath string, conf *config.Config) error {
 			dstDir := filepath.Join("_dump_", celDir, palDir, trnDir)
 
 			// Dump CEL image.
-			if err := dumpArchiveWithPal(dstDir, celPath, trnPal); err != nil {
+			if err := dumpArchiveWithPal(dstDir, fsys, relCelPath, trnPal); err != nil {
 				return errors.WithStack(err)
 			}
 		}
@@ -143,15 +158,15 @@ func dumpArchive(mpqDir, relCelPath string, conf *config.Config) error {
 
 // dumpArchiveWithPal converts the given CEL archive to a set of PNG images,
 // using colours from the given palette.
-func dumpArchiveWithPal(dstDir, celPath string, pal color.Palette) error {
-	archiveImgs, err := cel.DecodeArchive(celPath, pal)
+func dumpArchiveWithPal(dstDir string, fsys fs.FS, relCelPath string, pal color.Palette) error {
+	archiveImgs, err := cel.DecodeArchiveFS(fsys, relCelPath, pal)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
 		return errors.WithStack(err)
 	}
-	celName := pathutil.FileName(celPath)
+	celName := pathutil.FileName(relCelPath)
 	for i, archiveImg := range archiveImgs {
 		archiveName := fmt.Sprintf("%s_%d", celName, i)
 		archiveDir := filepath.Join(dstDir, archiveName)
@@ -174,7 +189,7 @@ func dumpArchiveWithPal(dstDir, celPath string, pal color.Palette) error {
 }
 
 // dumpCel converts the given CEL file to a set of PNG images.
-func dumpCel(mpqDir, relCelPath string, conf *config.Config) error {
+func dumpCel(fsys fs.FS, relCelPath string, conf *config.Config) error {
 	dbg.Printf("Converting %q.", relCelPath)
 	// TODO: Remove temporary hack when the config package containing accurate
 	// palette descriptions.
@@ -183,8 +198,7 @@ func dumpCel(mpqDir, relCelPath string, conf *config.Config) error {
 	}
 	for _, relPalPath := range conf.Pals {
 		// Parse PAL file.
-		palPath := filepath.Join(mpqDir, relPalPath)
-		pal, err := cel.ParsePal(palPath)
+		pal, err := cel.ParsePalFS(fsys, relPalPath)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -198,16 +212,14 @@ func dumpCel(mpqDir, relCelPath string, conf *config.Config) error {
 		dstDir := filepath.Join("_dump_", celDir, palDir)
 
 		// Dump CEL image.
-		celPath := filepath.Join(mpqDir, relCelPath)
-		if err := dumpCelWithPal(dstDir, celPath, pal); err != nil {
+		if err := dumpCelWithPal(dstDir, fsys, relCelPath, pal); err != nil {
 			return errors.WithStack(err)
 		}
 
 		// Dump CEL image with colour transitions.
 		for _, relTrnPath := range conf.Trns {
 			// Parse TRN file.
-			trnPath := filepath.Join(mpqDir, relTrnPath)
-			trn, err := cel.ParseTrn(trnPath)
+			trn, err := cel.ParseTrnFS(fsys, relTrnPath)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -218,7 +230,7 @@ func dumpCel(mpqDir, relCelPath string, conf *config.Config) error {
 			dstDir := filepath.Join("_dump_", celDir, palDir, trnDir)
 
 			// Dump CEL image.
-			if err := dumpCelWithPal(dstDir, celPath, trnPal); err != nil {
+			if err := dumpCelWithPal(dstDir, fsys, relCelPath, trnPal); err != nil {
 				return errors.WithStack(err)
 			}
 		}
@@ -228,8 +240,8 @@ func dumpCel(mpqDir, relCelPath string, conf *config.Config) error {
 
 // dumpCelWithPal converts the CEL file to a set of PNG images, using colours
 // from the given palette.
-func dumpCelWithPal(dstDir, celPath string, pal color.Palette) error {
-	imgs, err := cel.DecodeAll(celPath, pal)
+func dumpCelWithPal(dstDir string, fsys fs.FS, relCelPath string, pal color.Palette) error {
+	imgs, err := cel.DecodeAllFS(fsys, relCelPath, pal)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -237,7 +249,7 @@ func dumpCelWithPal(dstDir, celPath string, pal color.Palette) error {
 		return errors.WithStack(err)
 	}
 	for i, img := range imgs {
-		celName := pathutil.FileName(celPath)
+		celName := pathutil.FileName(relCelPath)
 		pngName := celName + ".png"
 		if len(imgs) > 1 {
 			pngName = fmt.Sprintf("%s_%04d.png", celName, i+1)
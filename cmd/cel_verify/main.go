@@ -0,0 +1,94 @@
+// The cel_verify tool checks the CEL and CL2 files of "diabdat.mpq" against
+// their image configs.
+//
+// Each mismatch is printed to standard output as a JSON object on a line of its
+// own, with the fields "path", "image", "frame", "check", "want", "got" and
+// "msg".
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mewkiz/pkg/term"
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/assets"
+	"github.com/sanctuary/formats/image/cel"
+)
+
+// dbg represents a logger with the "cel_verify:" prefix, which logs debug
+// messages to standard error.
+var dbg = log.New(os.Stderr, term.GreenBold("cel_verify:")+" ", 0)
+
+func usage() {
+	const use = `
+Check CEL and CL2 files against their image configs.
+
+Usage:
+
+	cel_verify [OPTION]... [FILE]...
+
+If no files are specified, every CEL and CL2 file with an image config is
+checked.
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Parse command line flags.
+	var (
+		// mpqDir specifies the path to an extracted "diabdat.mpq".
+		mpqDir string
+	)
+	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `path to extracted "diabdat.mpq"`)
+	flag.Usage = usage
+	flag.Parse()
+
+	// Determine relative CEL paths.
+	relCelPaths := flag.Args()
+	if len(relCelPaths) == 0 {
+		relCelPaths = assets.RelPaths(assets.CEL, assets.CL2)
+	}
+	sort.Strings(relCelPaths)
+
+	// Verify CEL and CL2 files.
+	enc := json.NewEncoder(os.Stdout)
+	total := 0
+	for _, relCelPath := range relCelPaths {
+		n, err := verify(enc, mpqDir, relCelPath)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		total += n
+	}
+	dbg.Printf("%d mismatches in %d files.", total, len(relCelPaths))
+	if total > 0 {
+		os.Exit(1)
+	}
+}
+
+// verify checks the given CEL file against its image config, and prints each
+// mismatch to the JSON encoder. It returns the number of mismatches.
+func verify(enc *json.Encoder, mpqDir, relCelPath string) (int, error) {
+	celPath := filepath.Join(mpqDir, relCelPath)
+	mismatches, err := cel.Verify(celPath)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	for _, m := range mismatches {
+		// Report paths relative to "diabdat.mpq".
+		m.Path = relCelPath
+		if err := enc.Encode(m); err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	return len(mismatches), nil
+}
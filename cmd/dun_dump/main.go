@@ -0,0 +1,243 @@
+// The dun_dump tool converts DUN files to PNG images (*.dun -> *.png).
+//
+// Output files are stored to the "_dump_/_dungeons_" directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewkiz/pkg/pathutil"
+	"github.com/mewkiz/pkg/term"
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/assets"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/render"
+	"github.com/sanctuary/formats/repair"
+)
+
+// dbg represents a logger with the "dun_dump:" prefix, which logs debug
+// messages to standard error.
+var dbg = log.New(os.Stderr, term.BlueBold("dun_dump:")+" ", 0)
+
+func usage() {
+	const use = `
+Convert DUN files to PNG images (*.dun -> *.png).
+
+Usage:
+
+	dun_dump [OPTION]... FILE.dun...
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Parse command line flags.
+	var (
+		// mpqDir specifies the path to an extracted "diabdat.mpq".
+		mpqDir string
+		// all specifies whether to dump all DUN files.
+		all bool
+		// transList specifies a comma-separated list of transparency regions
+		// drawn with transparent walls.
+		transList string
+		// lights specifies the light sources of the rendered dungeon maps.
+		lights lightList
+		// sprites specifies whether to draw the objects and monsters placed by
+		// DUN files.
+		sprites bool
+		// fix specifies whether to repair the known broken files of
+		// "diabdat.mpq" in memory.
+		fix bool
+	)
+	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `path to extracted "diabdat.mpq"`)
+	flag.BoolVar(&all, "a", false, "dump all DUN files")
+	flag.Var(&lights, "light", `light source "x,y,radius" in dungeon piece coordinates (may be repeated)`)
+	flag.BoolVar(&sprites, "sprites", false, "draw objects and monsters placed by DUN files")
+	flag.StringVar(&transList, "trans", "", `comma-separated transparency regions drawn with transparent walls (e.g. "1,2")`)
+	flag.BoolVar(&fix, "fix", false, `repair known broken files of "diabdat.mpq" in memory`)
+	flag.Usage = usage
+	flag.Parse()
+	if !all && flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Determine relative DUN paths.
+	var relDunPaths []string
+	if all {
+		relDunPaths = assets.RelPaths(assets.DUN)
+	} else {
+		relDunPaths = flag.Args()
+	}
+	sort.Strings(relDunPaths)
+
+	// Parse transparency regions.
+	transRegions, err := parseTransRegions(transList)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	opts := &options{
+		transRegions: transRegions,
+		lights:       lights,
+		sprites:      sprites,
+	}
+
+	// Open game assets.
+	fsys := os.DirFS(mpqDir)
+	if fix {
+		fsys = repair.FS(fsys)
+	}
+
+	// Convert DUN files.
+	levels := make(map[string]*render.Level)
+	for _, relDunPath := range relDunPaths {
+		if err := dumpDun(levels, fsys, relDunPath, opts); err != nil {
+			log.Fatalf("%+v", err)
+		}
+	}
+}
+
+// parseTransRegions parses the given comma-separated list of transparency
+// regions.
+func parseTransRegions(s string) ([]uint16, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	var regions []uint16
+	for _, field := range strings.Split(s, ",") {
+		region, err := strconv.ParseUint(strings.TrimSpace(field), 10, 16)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		regions = append(regions, uint16(region))
+	}
+	return regions, nil
+}
+
+// lightList is a list of light sources, which implements flag.Value.
+type lightList []render.Light
+
+// String returns a string representation of the light sources.
+func (ls *lightList) String() string {
+	var fields []string
+	for _, l := range *ls {
+		fields = append(fields, fmt.Sprintf("%d,%d,%d", l.X, l.Y, l.Radius))
+	}
+	return strings.Join(fields, " ")
+}
+
+// Set parses the light source "x,y,radius" and appends it to the list.
+func (ls *lightList) Set(s string) error {
+	var l render.Light
+	if _, err := fmt.Sscanf(s, "%d,%d,%d", &l.X, &l.Y, &l.Radius); err != nil {
+		return errors.Errorf("invalid light source %q; expected \"x,y,radius\"", s)
+	}
+	*ls = append(*ls, l)
+	return nil
+}
+
+// options specifies how dungeon maps are rendered.
+type options struct {
+	// Transparency regions drawn with transparent walls.
+	transRegions []uint16
+	// Light sources of the dungeon maps; or nil to render fully lit.
+	lights []render.Light
+	// Draw the objects and monsters placed by DUN files.
+	sprites bool
+}
+
+// dumpDun converts the given DUN file to a PNG image, rendered as specified by
+// opts. The graphics of each level type are loaded once and cached in levels.
+func dumpDun(levels map[string]*render.Level, fsys fs.FS, relDunPath string, opts *options) error {
+	dbg.Printf("Converting %q.", relDunPath)
+
+	// Parse DUN file.
+	d, err := dun.ParseFS(fsys, relDunPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Load level graphics.
+	name, err := render.LevelName(relDunPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	level, ok := levels[name]
+	if !ok {
+		level, err = loadLevel(fsys, name)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		levels[name] = level
+	}
+
+	// Render dungeon map.
+	scene := &render.Scene{
+		Tiles:        d.Tiles,
+		Trans:        d.Transparency,
+		TransRegions: opts.transRegions,
+	}
+	if len(opts.lights) > 0 {
+		// The light tables of hell shade its blood colours differently.
+		scene.LightTables = cel.LightTables(name == "l4")
+		scene.Lights = opts.lights
+		scene.Ambient = cel.NLightLevels - 1
+	}
+	if opts.sprites {
+		placements, err := render.LoadSpritesFS(fsys, d, level.Pal)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		scene.Sprites = placements
+	}
+	img, err := level.RenderScene(scene)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dstDir := filepath.Join("_dump_", "_dungeons_", name)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	pngName := pathutil.FileName(relDunPath) + ".png"
+	pngPath := filepath.Join(dstDir, pngName)
+	if err := imgutil.WriteFile(pngPath, img); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// loadLevel loads the graphics of the given level type, using the first
+// palette of the level CEL file.
+func loadLevel(fsys fs.FS, name string) (*render.Level, error) {
+	celName := name + ".cel"
+	conf, err := config.Get(celName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(conf.Pals) == 0 {
+		return nil, errors.Errorf("unable to locate palette of %q", celName)
+	}
+	pal, err := cel.ParsePalFS(fsys, conf.Pals[0])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	level, err := render.LoadFS(fsys, name, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return level, nil
+}
@@ -0,0 +1,214 @@
+// The dun_tiled tool converts DUN files to Tiled maps (*.dun -> *.tmx), and
+// edited Tiled maps back to DUN files (*.tmx -> *.dun).
+//
+// Output files are stored to the "_dump_/_tiled_" directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewkiz/pkg/pathutil"
+	"github.com/mewkiz/pkg/term"
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/assets"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/render"
+	"github.com/sanctuary/formats/level/tiled"
+)
+
+// dbg represents a logger with the "dun_tiled:" prefix, which logs debug
+// messages to standard error.
+var dbg = log.New(os.Stderr, term.GreenBold("dun_tiled:")+" ", 0)
+
+func usage() {
+	const use = `
+Convert DUN files to Tiled maps (*.dun -> *.tmx), and back (*.tmx -> *.dun).
+
+Usage:
+
+	dun_tiled [OPTION]... FILE.dun...
+	dun_tiled -import FILE.tmx...
+
+Each map references the tileset of its level type (e.g. "l1.tsx"), which is
+exported alongside the maps as a TSX file and an atlas PNG image.
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Parse command line flags.
+	var (
+		// mpqDir specifies the path to an extracted "diabdat.mpq".
+		mpqDir string
+		// all specifies whether to convert all DUN files.
+		all bool
+		// imp specifies whether to import Tiled maps to DUN files.
+		imp bool
+	)
+	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `path to extracted "diabdat.mpq"`)
+	flag.BoolVar(&all, "a", false, "convert all DUN files")
+	flag.BoolVar(&imp, "import", false, "import Tiled maps to DUN files")
+	flag.Usage = usage
+	flag.Parse()
+	if !all && flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Import Tiled maps.
+	if imp {
+		for _, tmxPath := range flag.Args() {
+			if err := importMap(tmxPath); err != nil {
+				log.Fatalf("%+v", err)
+			}
+		}
+		return
+	}
+
+	// Determine relative DUN paths.
+	var relDunPaths []string
+	if all {
+		relDunPaths = assets.RelPaths(assets.DUN)
+	} else {
+		relDunPaths = flag.Args()
+	}
+	sort.Strings(relDunPaths)
+
+	// Export DUN files.
+	exported := make(map[string]bool)
+	for _, relDunPath := range relDunPaths {
+		if err := exportDun(exported, relDunPath, mpqDir); err != nil {
+			log.Fatalf("%+v", err)
+		}
+	}
+}
+
+// exportDun converts the given DUN file to a Tiled map. The tileset of each
+// level type is exported once, as recorded by exported.
+func exportDun(exported map[string]bool, relDunPath, mpqDir string) error {
+	dbg.Printf("Converting %q.", relDunPath)
+
+	// Parse DUN file.
+	dunPath := filepath.Join(mpqDir, relDunPath)
+	d, err := dun.Parse(dunPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	name, err := render.LevelName(relDunPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dstDir := filepath.Join("_dump_", "_tiled_", name)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Export tileset.
+	tsxName := name + ".tsx"
+	if !exported[name] {
+		if err := exportTileset(dstDir, name, mpqDir); err != nil {
+			return errors.WithStack(err)
+		}
+		exported[name] = true
+	}
+
+	// Export map.
+	tmxPath := filepath.Join(dstDir, pathutil.FileName(relDunPath)+".tmx")
+	f, err := os.Create(tmxPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := tiled.EncodeMap(f, tiled.ExportMap(d, tsxName)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// exportTileset exports the tileset of the given level type to dstDir, using
+// the first palette of the level CEL file.
+func exportTileset(dstDir, name, mpqDir string) error {
+	dbg.Printf("Exporting tileset %q.", name)
+	celName := name + ".cel"
+	conf, err := config.Get(celName)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(conf.Pals) == 0 {
+		return errors.Errorf("unable to locate palette of %q", celName)
+	}
+	pal, err := cel.ParsePal(filepath.Join(mpqDir, conf.Pals[0]))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	level, err := render.Load(mpqDir, name, pal)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	pngName := name + ".png"
+	ts, atlas, err := tiled.ExportTileset(name, pngName, level.Tiles, level.DPieces, level.Frames)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := imgutil.WriteFile(filepath.Join(dstDir, pngName), atlas); err != nil {
+		return errors.WithStack(err)
+	}
+	f, err := os.Create(filepath.Join(dstDir, name+".tsx"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := tiled.EncodeTileset(f, ts); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// importMap converts the given Tiled map to a DUN file, stored next to the
+// Tiled map.
+func importMap(tmxPath string) error {
+	dbg.Printf("Importing %q.", tmxPath)
+	f, err := os.Open(tmxPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	m, err := tiled.DecodeMap(f)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	d, err := tiled.ImportMap(m)
+	if err != nil {
+		return errors.Wrapf(err, "unable to import %q", tmxPath)
+	}
+	dunPath := pathutil.TrimExt(tmxPath) + ".dun"
+	w, err := os.Create(dunPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer w.Close()
+	if err := dun.Encode(w, d); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -7,6 +7,9 @@ import (
 	"flag"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,9 +19,15 @@ import (
 	"github.com/mewkiz/pkg/pathutil"
 	"github.com/mewkiz/pkg/term"
 	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/assets"
 	"github.com/sanctuary/formats/image/cel"
 	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/internal/assetfs"
+	"github.com/sanctuary/formats/level/atlas"
 	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/sol"
+	"github.com/sanctuary/formats/level/til"
+	"github.com/sanctuary/formats/repair"
 )
 
 // dbg represents a logger with the "min_dump:" prefix, which logs debug
@@ -33,6 +42,22 @@ Usage:
 
 	min_dump [OPTION]... FILE.min...
 
+The -atlas flag packs the dungeon pieces of the MIN file and the tiles of the
+level's TIL file into atlas page images of at most 2048x2048 pixels, with a
+JSON index of the page, rectangle and source blocks of each entry. Identical
+images are stored once.
+
+The -sol flag overlays the dungeon piece properties of the level's SOL file as
+coloured squares in the top-left corner of each dungeon piece:
+
+	red     solid
+	yellow  blocks light
+	blue    blocks missiles
+	green   transparent
+	cyan    transparent left wall
+	magenta transparent right wall
+	white   trap
+
 Flags:
 `
 	fmt.Fprintln(os.Stderr, use[1:])
@@ -44,11 +69,25 @@ func main() {
 	var (
 		// mpqDir specifies the path to an extracted "diabdat.mpq".
 		mpqDir string
+		// mpqPath specifies the path to "diabdat.mpq".
+		mpqPath string
 		// all specifies whether to dump all MIN files.
 		all bool
+		// overlaySol specifies whether to overlay SOL dungeon piece properties.
+		overlaySol bool
+		// packAtlas specifies whether to pack dungeon pieces and tiles into an
+		// atlas.
+		packAtlas bool
+		// fix specifies whether to repair the known broken files of
+		// "diabdat.mpq" in memory.
+		fix bool
 	)
 	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `Path to extracted "diabdat.mpq".`)
+	flag.StringVar(&mpqPath, "mpq", "", `Path to "diabdat.mpq" (takes precedence over -mpqdir).`)
 	flag.BoolVar(&all, "a", false, "dump all MIN files")
+	flag.BoolVar(&overlaySol, "sol", false, "overlay SOL dungeon piece properties")
+	flag.BoolVar(&packAtlas, "atlas", false, "pack dungeon pieces and tiles into an atlas image with JSON index")
+	flag.BoolVar(&fix, "fix", false, `repair known broken files of "diabdat.mpq" in memory`)
 	flag.Usage = usage
 	flag.Parse()
 	if !all && flag.NArg() == 0 {
@@ -59,38 +98,53 @@ func main() {
 	// Determine relative MIN paths.
 	var relMinPaths []string
 	if all {
-		relMinPaths = []string{
-			"levels/l1data/l1.min",
-			"levels/l2data/l2.min",
-			"levels/l3data/l3.min",
-			"levels/l4data/l4.min",
-			"levels/towndata/town.min",
-		}
+		relMinPaths = assets.RelPaths(assets.MIN)
 	} else {
 		relMinPaths = flag.Args()
 	}
 	sort.Strings(relMinPaths)
 
+	// Open game assets.
+	fsys, err := assetfs.Open(mpqPath, mpqDir)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if fix {
+		fsys = repair.FS(fsys)
+	}
+
 	// Parse MIN files.
 	for _, relMinPath := range relMinPaths {
-		if err := dumpMin(relMinPath, mpqDir); err != nil {
+		if err := dumpMin(fsys, relMinPath, overlaySol, packAtlas); err != nil {
 			log.Fatalf("%+v", err)
 		}
 	}
 }
 
 // dumpMin decodes the given MIN file and displays its contents to standard
-// output.
-func dumpMin(relMinPath, mpqDir string) error {
+// output. If overlaySol is set, the dungeon piece properties of the
+// corresponding SOL file are overlaid on the dungeon piece images. If packAtlas
+// is set, the dungeon pieces and the tiles of the corresponding TIL file are
+// packed into an atlas instead.
+func dumpMin(fsys fs.FS, relMinPath string, overlaySol, packAtlas bool) error {
 	dbg.Printf("Converting %q.", relMinPath)
 
 	// Parse MIN file.
-	minPath := filepath.Join(mpqDir, relMinPath)
-	dpieces, err := min.Parse(minPath)
+	dpieces, err := min.ParseFS(fsys, relMinPath)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	// Parse SOL file.
+	var props []sol.Props
+	if overlaySol {
+		relSolPath := pathutil.TrimExt(relMinPath) + ".sol"
+		props, err = sol.ParseFS(fsys, relSolPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	// Parse level CEL frames.
 	name := pathutil.FileName(relMinPath)
 	relCelPath := fmt.Sprintf("levels/%sdata/%s.cel", name, name)
@@ -101,8 +155,7 @@ func dumpMin(relMinPath, mpqDir string) error {
 	}
 	for _, relPalPath := range conf.Pals {
 		// Parse PAL file.
-		palPath := filepath.Join(mpqDir, relPalPath)
-		pal, err := cel.ParsePal(palPath)
+		pal, err := cel.ParsePalFS(fsys, relPalPath)
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -114,15 +167,28 @@ func dumpMin(relMinPath, mpqDir string) error {
 		}
 
 		// Parse CEL image.
-		celPath := filepath.Join(mpqDir, relCelPath)
-		levelFrames, err := cel.DecodeAll(celPath, pal)
+		levelFrames, err := cel.DecodeAllFS(fsys, relCelPath, pal)
 		if err != nil {
 			return errors.WithStack(err)
 		}
 
+		// Pack dungeon pieces and tiles into atlas.
+		if packAtlas {
+			relTilPath := pathutil.TrimExt(relMinPath) + ".til"
+			tiles, err := til.ParseFS(fsys, relTilPath)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			dstDir := filepath.Join("_dump_", "_atlas_", name, palDir)
+			if err := dumpAtlas(dstDir, name, dpieces, tiles, levelFrames); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+
 		// Dump dungeon pieces of MIN file.
 		dstDir := filepath.Join("_dump_", "_dpieces_", name, palDir)
-		if err := dumpDPieces(dstDir, dpieces, levelFrames); err != nil {
+		if err := dumpDPieces(dstDir, dpieces, props, levelFrames); err != nil {
 			return errors.WithStack(err)
 		}
 	}
@@ -131,8 +197,8 @@ func dumpMin(relMinPath, mpqDir string) error {
 
 // dumpDPieces converts the dungeon pieces of a MIN file to a set of PNG
 // images, where each non-empty block corresponds to a CEL frame from
-// levelFrames.
-func dumpDPieces(dstDir string, dpieces []min.DPiece, levelFrames []image.Image) error {
+// levelFrames. The dungeon piece properties are overlaid if props is non-nil.
+func dumpDPieces(dstDir string, dpieces []min.DPiece, props []sol.Props, levelFrames []image.Image) error {
 	if err := os.MkdirAll(dstDir, 0755); err != nil {
 		return errors.WithStack(err)
 	}
@@ -140,10 +206,75 @@ func dumpDPieces(dstDir string, dpieces []min.DPiece, levelFrames []image.Image)
 		dpieceID := i + 1
 		pngName := fmt.Sprintf("dpiece_%04d.png", dpieceID)
 		pngPath := filepath.Join(dstDir, pngName)
-		img := dpiece.Image(levelFrames)
+		img, err := dpiece.SafeImage(levelFrames)
+		if err != nil {
+			return errors.Wrapf(err, "invalid dungeon piece %d", dpieceID)
+		}
+		if i < len(props) {
+			img = overlayProps(img, props[i])
+		}
 		if err := imgutil.WriteFile(pngPath, img); err != nil {
 			return errors.WithStack(err)
 		}
 	}
 	return nil
 }
+
+// dumpAtlas packs the given dungeon pieces and tiles into an atlas, and stores
+// the atlas page images and its JSON index to dstDir.
+func dumpAtlas(dstDir, name string, dpieces []min.DPiece, tiles []til.Tile, levelFrames []image.Image) error {
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	pages, index, err := atlas.Pack(name, dpieces, tiles, levelFrames)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i, page := range pages {
+		if err := imgutil.WriteFile(filepath.Join(dstDir, index.Pages[i].Image), page); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	f, err := os.Create(filepath.Join(dstDir, name+".json"))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	if err := atlas.EncodeIndex(f, index); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// overlayProps returns a copy of the given dungeon piece image, with its
+// properties overlaid as coloured squares in the top-left corner.
+func overlayProps(src image.Image, p sol.Props) image.Image {
+	const size = 6
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	flags := []struct {
+		set bool
+		c   color.Color
+	}{
+		{set: p.Solid, c: color.RGBA{R: 0xFF, A: 0xFF}},
+		{set: p.BlockLight, c: color.RGBA{R: 0xFF, G: 0xFF, A: 0xFF}},
+		{set: p.BlockMissile, c: color.RGBA{B: 0xFF, A: 0xFF}},
+		{set: p.Transparent, c: color.RGBA{G: 0xFF, A: 0xFF}},
+		{set: p.TransparentLeft, c: color.RGBA{G: 0xFF, B: 0xFF, A: 0xFF}},
+		{set: p.TransparentRight, c: color.RGBA{R: 0xFF, B: 0xFF, A: 0xFF}},
+		{set: p.Trap, c: color.White},
+	}
+	for i, flag := range flags {
+		if !flag.set {
+			continue
+		}
+		x := dst.Bounds().Min.X + i*(size+1)
+		y := dst.Bounds().Min.Y
+		r := image.Rect(x, y, x+size, y+size)
+		draw.Draw(dst, r, image.NewUniform(flag.c), image.ZP, draw.Src)
+	}
+	return dst
+}
@@ -0,0 +1,134 @@
+// The mpq_pack tool creates MPQ archives from directories (e.g. diabdat/ ->
+// diabdat.mpq).
+//
+// The file names of the archive are stored in its list file, and are relative
+// to the given directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mewkiz/pkg/term"
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/archive/mpq"
+)
+
+// dbg represents a logger with the "mpq_pack:" prefix, which logs debug
+// messages to standard error.
+var dbg = log.New(os.Stderr, term.GreenBold("mpq_pack:")+" ", 0)
+
+func usage() {
+	const use = `
+Create MPQ archives from directories (e.g. diabdat/ -> diabdat.mpq).
+
+Usage:
+
+	mpq_pack -o FILE.mpq [OPTION]... DIR
+
+The output MPQ archive must be located outside of DIR, and is only overwritten
+if the -f flag is set.
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Parse command line flags.
+	var (
+		// output specifies the path of the output MPQ archive.
+		output string
+		// compress specifies whether to compress the sectors of files.
+		compress bool
+		// force specifies whether to overwrite an existing output file.
+		force bool
+	)
+	flag.StringVar(&output, "o", "", "path of output MPQ archive (required)")
+	flag.BoolVar(&compress, "compress", true, "compress sectors using PKWARE DCL implode")
+	flag.BoolVar(&force, "f", false, "overwrite existing output MPQ archive")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 || len(output) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	dir := flag.Arg(0)
+
+	// Create MPQ archive.
+	if err := pack(output, dir, compress, force); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+// pack creates an MPQ archive at the given output path, containing the files
+// of the given directory. An existing output file is overwritten if force is
+// set.
+func pack(output, dir string, compress, force bool) error {
+	dbg.Printf("Creating %q from %q.", output, dir)
+	inside, err := within(output, dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if inside {
+		return errors.Errorf("output MPQ archive %q located inside of %q", output, dir)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(output, flags, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return errors.Errorf("output MPQ archive %q already exists; use -f to overwrite", output)
+		}
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	w, err := mpq.NewWriter(f, compress)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.AddFS(os.DirFS(dir)); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// within reports whether the given path is located inside of the given
+// directory, after resolving symbolic links of the directory and of the parent
+// directory of the path.
+func within(path, dir string) (bool, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	path, err = filepath.Abs(filepath.Join(parent, filepath.Base(path)))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
@@ -0,0 +1,97 @@
+// The town_dump tool renders the complete map of Tristram to a PNG image.
+//
+// Output files are stored to the "_dump_/_dungeons_" directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mewkiz/pkg/imgutil"
+	"github.com/mewkiz/pkg/term"
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/town"
+)
+
+// dbg represents a logger with the "town_dump:" prefix, which logs debug
+// messages to standard error.
+var dbg = log.New(os.Stderr, term.YellowBold("town_dump:")+" ", 0)
+
+func usage() {
+	const use = `
+Render the complete map of Tristram to a PNG image.
+
+Usage:
+
+	town_dump [OPTION]...
+
+The town map is assembled from the sector DUN files of "levels/towndata". With
+the -dun flag, the assembled map is additionally stored as a single DUN file,
+which may serve as a base for town modding.
+
+Flags:
+`
+	fmt.Fprintln(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	// Parse command line flags.
+	var (
+		// mpqDir specifies the path to an extracted "diabdat.mpq".
+		mpqDir string
+		// dunPath specifies the output path of the assembled DUN file.
+		dunPath string
+	)
+	flag.StringVar(&mpqDir, "mpqdir", "diabdat", `path to extracted "diabdat.mpq"`)
+	flag.StringVar(&dunPath, "dun", "", "store assembled town map to DUN file")
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := dumpTown(mpqDir, dunPath); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+// dumpTown renders the town map to a PNG image, and optionally stores the
+// assembled town map to the given DUN file.
+func dumpTown(mpqDir, dunPath string) error {
+	// Assemble town map.
+	t, err := town.Parse(mpqDir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(dunPath) > 0 {
+		dbg.Printf("Storing %q.", dunPath)
+		f, err := os.Create(dunPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		if err := dun.Encode(f, t); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := f.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	dbg.Println("Rendering town map.")
+	img, err := town.RenderMap(mpqDir, t)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dstDir := filepath.Join("_dump_", "_dungeons_", "town")
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	pngPath := filepath.Join(dstDir, "town.png")
+	if err := imgutil.WriteFile(pngPath, img); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
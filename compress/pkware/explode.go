@@ -0,0 +1,337 @@
+// Package pkware implements the PKWARE Data Compression Library (DCL) format,
+// as used by the "implode" compression of MPQ archives and the save files of
+// the game.
+//
+// Below follows a pseudo-code description of the compressed format.
+//
+//    // A compressed stream starts with a two-byte header, followed by a
+//    // sequence of codes stored in a bit stream; least significant bit first.
+//    type DCL struct {
+//       // Literal mode; 0 (uncoded literals) or 1 (Huffman coded literals).
+//       lit uint8
+//       // Dictionary size in bits; 4 (1 KB), 5 (2 KB) or 6 (4 KB).
+//       dict uint8
+//       // Codes; terminated by an end-of-stream length code (519).
+//       codes []Code
+//    }
+//
+//    // A Code is either a literal byte or a length-distance pair, referring
+//    // to previous output.
+//    //
+//    //    0 + literal (8 bits; or Huffman coded if lit == 1)
+//    //    1 + length (Huffman coded, with extra bits) + distance (Huffman
+//    //        coded high bits, followed by dict low bits; or 2 low bits if
+//    //        length == 2)
+//    type Code struct{}
+//
+// The Huffman codes are stored with inverted bits, and the Huffman code lengths
+// of literals, lengths and distances are fixed by the format.
+package pkware
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Literal modes of compressed streams.
+const (
+	// Binary mode; literals are stored uncoded as 8 bits.
+	Binary = 0
+	// ASCII mode; literals are Huffman coded, favouring text.
+	ASCII = 1
+)
+
+// Limits of the format.
+const (
+	// Maximum number of bits of a Huffman code.
+	maxBits = 13
+	// Length code specifying the end of the stream.
+	endLen = 519
+	// Maximum distance of length-distance pairs, and the window size.
+	maxDist = 4096
+)
+
+// Compact Huffman code lengths of literals, lengths and distances. Each byte
+// specifies a code length (low 4 bits) and the number of consecutive symbols
+// of that code length minus one (high 4 bits).
+var (
+	litLen = []uint8{
+		11, 124, 8, 7, 28, 7, 188, 13, 76, 4, 10, 8, 12, 10, 12, 10, 8, 23, 8,
+		9, 7, 6, 7, 8, 7, 6, 55, 8, 23, 24, 12, 11, 7, 9, 11, 12, 6, 7, 22, 5,
+		7, 24, 6, 11, 9, 6, 7, 22, 7, 11, 38, 7, 9, 8, 25, 11, 8, 11, 9, 12,
+		8, 12, 5, 38, 5, 38, 5, 11, 7, 5, 6, 21, 6, 10, 53, 8, 7, 24, 10, 27,
+		44, 253, 253, 253, 252, 252, 252, 13, 12, 45, 12, 45, 12, 61, 12, 45,
+		44, 173,
+	}
+	lenLen  = []uint8{2, 35, 36, 53, 38, 23}
+	distLen = []uint8{2, 20, 53, 230, 247, 151, 248}
+)
+
+// Base lengths and number of extra bits of length codes.
+var (
+	lenBase  = [16]int{3, 2, 4, 5, 6, 7, 8, 9, 10, 12, 16, 24, 40, 72, 136, 264}
+	lenExtra = [16]uint{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}
+)
+
+// Huffman codes of literals, lengths and distances.
+var (
+	litCode  = newHuffman(litLen)
+	lenCode  = newHuffman(lenLen)
+	distCode = newHuffman(distLen)
+)
+
+// A huffman is a canonical Huffman code.
+type huffman struct {
+	// Code lengths of each symbol.
+	lengths []int
+	// Number of symbols of each code length.
+	count [maxBits + 1]int
+	// Symbols ordered by code length, and by symbol within each code length.
+	symbol []int
+	// Canonical code of each symbol; most significant bit first.
+	codes []int
+}
+
+// newHuffman returns the canonical Huffman code of the given compact code
+// lengths.
+func newHuffman(rep []uint8) *huffman {
+	var lengths []int
+	for _, b := range rep {
+		n := int(b>>4) + 1
+		for i := 0; i < n; i++ {
+			lengths = append(lengths, int(b&0x0F))
+		}
+	}
+	h := &huffman{lengths: lengths}
+	for _, l := range lengths {
+		h.count[l]++
+	}
+	var offs [maxBits + 2]int
+	for l := 1; l <= maxBits; l++ {
+		offs[l+1] = offs[l] + h.count[l]
+	}
+	h.symbol = make([]int, len(lengths))
+	for sym, l := range lengths {
+		if l != 0 {
+			h.symbol[offs[l]] = sym
+			offs[l]++
+		}
+	}
+	// The first code of each code length follows the last code of the
+	// preceding code length, and the codes of each code length are assigned in
+	// symbol order.
+	var next [maxBits + 1]int
+	for l, code := 1, 0; l <= maxBits; l++ {
+		next[l] = code
+		code = (code + h.count[l]) << 1
+	}
+	h.codes = make([]int, len(lengths))
+	for sym, l := range lengths {
+		if l != 0 {
+			h.codes[sym] = next[l]
+			next[l]++
+		}
+	}
+	return h
+}
+
+// A reader decompresses a DCL stream.
+type reader struct {
+	// Underlying reader of the compressed stream.
+	r io.ByteReader
+	// Bit buffer and number of bits in the bit buffer.
+	bitBuf uint32
+	bitCnt uint
+	// Literal mode and dictionary size in bits; read from the header.
+	lit, dict uint
+	// Window of previous output; of which the last n bytes are valid.
+	window [maxDist]byte
+	// Position of the next byte within the window.
+	pos int
+	// Number of bytes written to the window, capped at maxDist.
+	n int
+	// Remaining length and distance of the current length-distance pair.
+	copyLen, copyDist int
+	// Decoding error; io.EOF at the end of the stream.
+	err error
+}
+
+// NewReader returns a new reader which decompresses the DCL stream read from r.
+func NewReader(r io.Reader) io.Reader {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &reader{r: br}
+}
+
+// Read reads up to len(p) bytes of decompressed data into p.
+func (z *reader) Read(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.dict == 0 {
+		// Read header of the stream.
+		if err := z.readHeader(); err != nil {
+			z.err = err
+			return 0, err
+		}
+	}
+	n := 0
+	for n < len(p) {
+		if z.copyLen > 0 {
+			b := z.window[(z.pos-z.copyDist+maxDist)%maxDist]
+			z.put(b)
+			p[n] = b
+			n++
+			z.copyLen--
+			continue
+		}
+		b, done, err := z.next()
+		if err != nil {
+			z.err = err
+			return n, err
+		}
+		if done {
+			z.err = io.EOF
+			if n == 0 {
+				return 0, io.EOF
+			}
+			return n, nil
+		}
+		if z.copyLen > 0 {
+			// Length-distance pair; copied on the next iteration.
+			continue
+		}
+		z.put(b)
+		p[n] = b
+		n++
+	}
+	return n, nil
+}
+
+// readHeader reads the header of the DCL stream.
+func (z *reader) readHeader() error {
+	lit, err := z.bits(8)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if lit != Binary && lit != ASCII {
+		return errors.Errorf("invalid literal mode %d; expected 0 or 1", lit)
+	}
+	dict, err := z.bits(8)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if dict < 4 || dict > 6 {
+		return errors.Errorf("invalid dictionary size %d; expected 4-6", dict)
+	}
+	z.lit, z.dict = lit, dict
+	return nil
+}
+
+// next decodes the next code of the stream. For literals, the literal byte is
+// returned. For length-distance pairs, the copy length and distance are
+// recorded in z. The done flag is set at the end of the stream.
+func (z *reader) next() (b byte, done bool, err error) {
+	flag, err := z.bits(1)
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	if flag == 0 {
+		// Literal.
+		var v uint
+		if z.lit == ASCII {
+			v, err = z.decode(litCode)
+		} else {
+			v, err = z.bits(8)
+		}
+		if err != nil {
+			return 0, false, errors.WithStack(err)
+		}
+		return byte(v), false, nil
+	}
+	// Length-distance pair.
+	sym, err := z.decode(lenCode)
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	extra, err := z.bits(lenExtra[sym])
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	length := lenBase[sym] + int(extra)
+	if length == endLen {
+		return 0, true, nil
+	}
+	lowBits := z.dict
+	if length == 2 {
+		lowBits = 2
+	}
+	high, err := z.decode(distCode)
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	low, err := z.bits(lowBits)
+	if err != nil {
+		return 0, false, errors.WithStack(err)
+	}
+	dist := int(high<<lowBits|low) + 1
+	if dist > z.n {
+		return 0, false, errors.Errorf("invalid distance %d; expected <= %d", dist, z.n)
+	}
+	z.copyLen, z.copyDist = length, dist
+	return 0, false, nil
+}
+
+// put appends the given byte to the window.
+func (z *reader) put(b byte) {
+	z.window[z.pos] = b
+	z.pos = (z.pos + 1) % maxDist
+	if z.n < maxDist {
+		z.n++
+	}
+}
+
+// bits reads n bits from the stream; least significant bit first.
+func (z *reader) bits(n uint) (uint, error) {
+	for z.bitCnt < n {
+		b, err := z.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		z.bitBuf |= uint32(b) << z.bitCnt
+		z.bitCnt += 8
+	}
+	v := uint(z.bitBuf & (1<<n - 1))
+	z.bitBuf >>= n
+	z.bitCnt -= n
+	return v, nil
+}
+
+// decode decodes a symbol of the given Huffman code from the stream. The bits
+// of codes are inverted.
+func (z *reader) decode(h *huffman) (uint, error) {
+	code, first, index := 0, 0, 0
+	for l := 1; l <= maxBits; l++ {
+		bit, err := z.bits(1)
+		if err != nil {
+			return 0, err
+		}
+		code |= int(bit) ^ 1
+		count := h.count[l]
+		if code < first+count {
+			return uint(h.symbol[index+code-first]), nil
+		}
+		index += count
+		first += count
+		first <<= 1
+		code <<= 1
+	}
+	return 0, errors.New("invalid Huffman code")
+}
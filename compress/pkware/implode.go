@@ -0,0 +1,255 @@
+package pkware
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Limits of the compressor.
+const (
+	// Minimum length of length-distance pairs emitted by the compressor. The
+	// format supports pairs of length 2, but these rarely save any bits.
+	minLen = 3
+	// Maximum length of length-distance pairs.
+	maxLen = endLen - 1
+	// Number of bits of the hash of minLen consecutive bytes.
+	hashBits = 12
+	// Maximum number of previous positions searched for a match.
+	maxChain = 64
+)
+
+// A writer compresses data as a DCL stream.
+type writer struct {
+	// Underlying writer of the compressed stream.
+	w io.Writer
+	// Literal mode and dictionary size in bits.
+	lit, dict uint
+	// Maximum distance of length-distance pairs; 1 KB, 2 KB or 4 KB.
+	window int
+	// Uncompressed data; the bytes before pos are kept as history for
+	// length-distance pairs.
+	buf []byte
+	// Position of the next byte to compress within buf.
+	pos int
+	// Absolute position of buf[0] within the uncompressed data.
+	base int
+	// Most recent absolute position plus one of each hash; or 0 if not
+	// present.
+	head [1 << hashBits]int
+	// Preceding absolute position plus one of the same hash, indexed by
+	// absolute position modulo maxDist.
+	prev [maxDist]int
+	// Bit buffer and number of bits in the bit buffer.
+	bitBuf uint32
+	bitCnt uint
+	// Compressed output pending write to w.
+	out []byte
+	// Write error; or errClosed after Close.
+	err error
+}
+
+// errClosed is returned when writing to a closed writer.
+var errClosed = errors.New("pkware: write to closed writer")
+
+// NewWriter returns a new writer which compresses data written to it as a DCL
+// stream to w, using the given literal mode (Binary or ASCII) and dictionary
+// size (1024, 2048 or 4096 bytes). The end of the stream is written on Close.
+func NewWriter(w io.Writer, lit, dictSize int) (io.WriteCloser, error) {
+	if lit != Binary && lit != ASCII {
+		return nil, errors.Errorf("invalid literal mode %d; expected %d or %d", lit, Binary, ASCII)
+	}
+	var dict uint
+	switch dictSize {
+	case 1024:
+		dict = 4
+	case 2048:
+		dict = 5
+	case 4096:
+		dict = 6
+	default:
+		return nil, errors.Errorf("invalid dictionary size %d; expected 1024, 2048 or 4096", dictSize)
+	}
+	z := &writer{
+		w:      w,
+		lit:    uint(lit),
+		dict:   dict,
+		window: dictSize,
+	}
+	z.out = append(z.out, byte(lit), byte(dict))
+	return z, nil
+}
+
+// Write compresses the given data.
+func (z *writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	z.buf = append(z.buf, p...)
+	// Compress data which has a full lookahead of maxLen bytes.
+	for len(z.buf)-z.pos >= maxLen {
+		z.step()
+	}
+	// Discard history beyond the window.
+	if z.pos > 2*maxDist {
+		n := z.pos - maxDist
+		z.buf = append(z.buf[:0], z.buf[n:]...)
+		z.pos -= n
+		z.base += n
+	}
+	if err := z.flush(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close compresses the remaining data and writes the end of the stream. It does
+// not close the underlying writer.
+func (z *writer) Close() error {
+	if z.err != nil {
+		if z.err == errClosed {
+			return nil
+		}
+		return z.err
+	}
+	for z.pos < len(z.buf) {
+		z.step()
+	}
+	// End of stream.
+	z.putLen(endLen)
+	if z.bitCnt > 0 {
+		z.out = append(z.out, byte(z.bitBuf))
+		z.bitBuf, z.bitCnt = 0, 0
+	}
+	if err := z.flush(); err != nil {
+		return err
+	}
+	z.err = errClosed
+	return nil
+}
+
+// step emits the code of the next byte to compress; either a literal or a
+// length-distance pair.
+func (z *writer) step() {
+	length, dist := z.match()
+	if length < minLen {
+		z.putBits(0, 1)
+		b := z.buf[z.pos]
+		if z.lit == ASCII {
+			z.putCode(litCode, int(b))
+		} else {
+			z.putBits(uint32(b), 8)
+		}
+		z.insert(z.pos)
+		z.pos++
+		return
+	}
+	z.putLen(length)
+	d := dist - 1
+	z.putCode(distCode, d>>z.dict)
+	z.putBits(uint32(d)&(1<<z.dict-1), z.dict)
+	for i := 0; i < length; i++ {
+		z.insert(z.pos)
+		z.pos++
+	}
+}
+
+// match returns the length and distance of the longest match of the data at
+// the current position within the window; or a length of 0 if not found.
+func (z *writer) match() (length, dist int) {
+	end := len(z.buf)
+	if end-z.pos > maxLen {
+		end = z.pos + maxLen
+	}
+	if end-z.pos < minLen {
+		return 0, 0
+	}
+	abs := z.base + z.pos
+	cand := z.head[hash(z.buf[z.pos:])]
+	for chain := 0; cand != 0 && chain < maxChain; chain++ {
+		candAbs := cand - 1
+		d := abs - candAbs
+		if d > z.window {
+			break
+		}
+		i := candAbs - z.base
+		n := 0
+		for z.pos+n < end && z.buf[i+n] == z.buf[z.pos+n] {
+			n++
+		}
+		if n > length {
+			length, dist = n, d
+			if z.pos+n == end {
+				break
+			}
+		}
+		cand = z.prev[candAbs%maxDist]
+	}
+	return length, dist
+}
+
+// insert records the given position of buf as the most recent occurrence of
+// its hash.
+func (z *writer) insert(pos int) {
+	if len(z.buf)-pos < minLen {
+		return
+	}
+	h := hash(z.buf[pos:])
+	abs := z.base + pos
+	z.prev[abs%maxDist] = z.head[h]
+	z.head[h] = abs + 1
+}
+
+// hash returns the hash of the first minLen bytes of buf.
+func hash(buf []byte) int {
+	v := uint32(buf[0])<<16 | uint32(buf[1])<<8 | uint32(buf[2])
+	return int(v * 2654435761 >> (32 - hashBits))
+}
+
+// putLen writes the length code of the given length.
+func (z *writer) putLen(length int) {
+	z.putBits(1, 1)
+	sym := 0
+	for i := range lenBase {
+		if lenBase[i] <= length && length < lenBase[i]+1<<lenExtra[i] {
+			sym = i
+			break
+		}
+	}
+	z.putCode(lenCode, sym)
+	z.putBits(uint32(length-lenBase[sym]), lenExtra[sym])
+}
+
+// putCode writes the code of the given symbol of the Huffman code. The bits of
+// codes are inverted.
+func (z *writer) putCode(h *huffman, sym int) {
+	code := h.codes[sym]
+	for i := h.lengths[sym] - 1; i >= 0; i-- {
+		z.putBits(uint32(code>>uint(i)&1^1), 1)
+	}
+}
+
+// putBits writes the n least significant bits of v; least significant bit
+// first.
+func (z *writer) putBits(v uint32, n uint) {
+	z.bitBuf |= v << z.bitCnt
+	z.bitCnt += n
+	for z.bitCnt >= 8 {
+		z.out = append(z.out, byte(z.bitBuf))
+		z.bitBuf >>= 8
+		z.bitCnt -= 8
+	}
+}
+
+// flush writes the pending compressed output to the underlying writer.
+func (z *writer) flush() error {
+	if len(z.out) == 0 {
+		return nil
+	}
+	if _, err := z.w.Write(z.out); err != nil {
+		z.err = errors.WithStack(err)
+		return z.err
+	}
+	z.out = z.out[:0]
+	return nil
+}
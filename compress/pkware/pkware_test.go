@@ -0,0 +1,141 @@
+package pkware_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/formats/compress/pkware"
+)
+
+func TestExplode(t *testing.T) {
+	golden := []struct {
+		in   []byte
+		want string
+	}{
+		// Example of blast.c by Mark Adler.
+		{in: []byte{0x00, 0x04, 0x82, 0x24, 0x25, 0x8F, 0x80, 0x7F}, want: "AIAIAIAIAIAIA"},
+	}
+	for _, g := range golden {
+		got, err := ioutil.ReadAll(pkware.NewReader(bytes.NewReader(g.in)))
+		if err != nil {
+			t.Errorf("unable to decompress %X; %v", g.in, err)
+			continue
+		}
+		if string(got) != g.want {
+			t.Errorf("output mismatch of %X; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+
+	// Truncated stream.
+	in := []byte{0x00, 0x04, 0x82, 0x24}
+	if _, err := ioutil.ReadAll(pkware.NewReader(bytes.NewReader(in))); err == nil {
+		t.Errorf("expected error for truncated stream, got nil")
+	}
+}
+
+func TestImplode(t *testing.T) {
+	// Output of the original implode for the example of blast.c.
+	want := []byte{0x00, 0x04, 0x82, 0x24, 0x25, 0x8F, 0x80, 0x7F}
+	got := implode(t, []byte("AIAIAIAIAIAIA"), pkware.Binary, 1024)
+	if !bytes.Equal(got, want) {
+		t.Errorf("output mismatch; expected %X, got %X", want, got)
+	}
+
+	// Invalid parameters.
+	if _, err := pkware.NewWriter(ioutil.Discard, 2, 1024); err == nil {
+		t.Errorf("expected error for invalid literal mode, got nil")
+	}
+	if _, err := pkware.NewWriter(ioutil.Discard, pkware.Binary, 8192); err == nil {
+		t.Errorf("expected error for invalid dictionary size, got nil")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 20000)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := []byte(strings.Repeat("Stay a while and listen. ", 1000))
+	golden := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "byte", data: []byte{0xFF}},
+		{name: "zeros", data: make([]byte, 10000)},
+		{name: "text", data: text},
+		{name: "random", data: random},
+		{name: "mixed", data: append(append([]byte(nil), random[:5000]...), text...)},
+	}
+	for _, g := range golden {
+		for _, lit := range []int{pkware.Binary, pkware.ASCII} {
+			for _, dictSize := range []int{1024, 2048, 4096} {
+				compressed := implode(t, g.data, lit, dictSize)
+				got, err := ioutil.ReadAll(pkware.NewReader(bytes.NewReader(compressed)))
+				if err != nil {
+					t.Errorf("%s (lit=%d, dict=%d): unable to decompress; %v", g.name, lit, dictSize, err)
+					continue
+				}
+				if !bytes.Equal(got, g.data) {
+					t.Errorf("%s (lit=%d, dict=%d): data mismatch after compress and decompress", g.name, lit, dictSize)
+				}
+				if g.name == "text" && len(compressed) > len(g.data)/10 {
+					t.Errorf("%s (lit=%d, dict=%d): poor compression; %d bytes compressed to %d", g.name, lit, dictSize, len(g.data), len(compressed))
+				}
+			}
+		}
+	}
+}
+
+func FuzzExplode(f *testing.F) {
+	f.Add([]byte{0x00, 0x04, 0x82, 0x24, 0x25, 0x8F, 0x80, 0x7F})
+	f.Add([]byte{0x01, 0x06, 0xFF, 0xFF})
+	f.Fuzz(func(t *testing.T, in []byte) {
+		// Invalid streams must be reported as errors.
+		ioutil.ReadAll(pkware.NewReader(bytes.NewReader(in)))
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add([]byte("AIAIAIAIAIAIA"), false, uint8(0))
+	f.Add([]byte(strings.Repeat("diablo", 100)), true, uint8(2))
+	f.Fuzz(func(t *testing.T, data []byte, ascii bool, dict uint8) {
+		lit := pkware.Binary
+		if ascii {
+			lit = pkware.ASCII
+		}
+		dictSize := 1024 << (dict % 3)
+		compressed := implode(t, data, lit, dictSize)
+		got, err := ioutil.ReadAll(pkware.NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			t.Fatalf("unable to decompress; %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("data mismatch after compress and decompress")
+		}
+	})
+}
+
+// implode compresses the given data using the provided literal mode and
+// dictionary size, writing the data in chunks of varying size.
+func implode(t *testing.T, data []byte, lit, dictSize int) []byte {
+	buf := &bytes.Buffer{}
+	w, err := pkware.NewWriter(buf, lit, dictSize)
+	if err != nil {
+		t.Fatalf("unable to create writer; %v", err)
+	}
+	for n := 1; len(data) > 0; n *= 2 {
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatalf("unable to compress data; %v", err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unable to close writer; %v", err)
+	}
+	return buf.Bytes()
+}
@@ -32,7 +32,9 @@ import (
 	"encoding/binary"
 	"image"
 	"image/color"
+	"io/fs"
 	"io/ioutil"
+	"path"
 	"path/filepath"
 
 	"github.com/pkg/errors"
@@ -40,23 +42,41 @@ import (
 )
 
 // DecodeArchive decodes the given CEL archive using colours from the provided
-// palette, and returns the sequential frames of the embedded CEL images.
+// palette, and returns the sequential frames of the embedded CEL images. The
+// frames are of type *IndexedImage.
 func DecodeArchive(path string, pal color.Palette) ([][]image.Image, error) {
-	// Locate image config data.
-	name := filepath.Base(path)
-	conf, err := config.Get(name)
+	// Read file contents.
+	archive, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	if conf.Nimgs == 0 {
-		return nil, errors.Errorf("invalid call for CEL image %q; use cel.DecodeAll instead")
-	}
+	return decodeArchive(filepath.Base(path), archive, pal)
+}
 
+// DecodeArchiveFS decodes the named CEL archive of fsys using colours from the
+// provided palette, and returns the sequential frames of the embedded CEL
+// images.
+func DecodeArchiveFS(fsys fs.FS, name string, pal color.Palette) ([][]image.Image, error) {
 	// Read file contents.
-	archive, err := ioutil.ReadFile(path)
+	archive, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return decodeArchive(path.Base(name), archive, pal)
+}
+
+// decodeArchive decodes the given contents of the named CEL archive using
+// colours from the provided palette, and returns the sequential frames of the
+// embedded CEL images.
+func decodeArchive(name string, archive []byte, pal color.Palette) ([][]image.Image, error) {
+	// Locate image config data.
+	conf, err := config.Get(name)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if conf.Nimgs == 0 {
+		return nil, errors.Errorf("invalid call for CEL image %q; use cel.DecodeAll instead", name)
+	}
 
 	// Read the contents of each embedded CEL image.
 	cels, err := readCELs(archive)
@@ -77,23 +97,39 @@ func DecodeArchive(path string, pal color.Palette) ([][]image.Image, error) {
 }
 
 // DecodeAll decodes the given CEL image using colours from the provided
-// palette, and returns the sequential frames.
+// palette, and returns the sequential frames. The frames are of type
+// *IndexedImage.
 func DecodeAll(path string, pal color.Palette) ([]image.Image, error) {
-	// Locate image config data.
-	name := filepath.Base(path)
-	conf, err := config.Get(name)
+	// Read file contents.
+	cel, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	if conf.Nimgs != 0 {
-		return nil, errors.Errorf("invalid call cel.DecodeAll for CEL archive %q; use cel.DecodeArchive instead", path)
-	}
+	return decodeCel(filepath.Base(path), cel, pal)
+}
 
+// DecodeAllFS decodes the named CEL image of fsys using colours from the
+// provided palette, and returns the sequential frames.
+func DecodeAllFS(fsys fs.FS, name string, pal color.Palette) ([]image.Image, error) {
 	// Read file contents.
-	cel, err := ioutil.ReadFile(path)
+	cel, err := fs.ReadFile(fsys, name)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return decodeCel(path.Base(name), cel, pal)
+}
+
+// decodeCel decodes the given contents of the named CEL image using colours
+// from the provided palette, and returns the sequential frames.
+func decodeCel(name string, cel []byte, pal color.Palette) ([]image.Image, error) {
+	// Locate image config data.
+	conf, err := config.Get(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if conf.Nimgs != 0 {
+		return nil, errors.Errorf("invalid call cel.DecodeAll for CEL archive %q; use cel.DecodeArchive instead", name)
+	}
 
 	// Decode CEL image frames.
 	return decodeAll(cel, pal, conf)
@@ -108,7 +144,8 @@ func decodeAll(cel []byte, pal color.Palette, conf *config.Config) ([]image.Imag
 		return nil, errors.WithStack(err)
 	}
 
-	// Decode frames.
+	// Decode frames, retaining the palette index of each pixel.
+	decodePal, srcPal := indexPal(pal)
 	var imgs []image.Image
 	for frameNum, frame := range frames {
 		// Determine decoder type based on image config and frame number.
@@ -128,7 +165,8 @@ func decodeAll(cel []byte, pal color.Palette, conf *config.Config) ([]image.Imag
 
 		// Decode the frame pixel data.
 		data := frame[conf.Header:] // Skip header contents if present.
-		img := decode(data, w, h, pal)
+		img := NewIndexedImage(image.Rect(0, 0, w, h), srcPal)
+		decode(newPixelDrawer(img, w, h), data, decodePal)
 		imgs = append(imgs, img)
 	}
 
@@ -10,9 +10,9 @@ import (
 	"path/filepath"
 	"testing"
 
-	"github.com/mewkiz/pkg/osutil"
 	"github.com/sanctuary/formats/image/cel"
 	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/internal/diabdat"
 )
 
 // palTest specifies the test cases of a given palette.
@@ -27,14 +27,8 @@ type palTest struct {
 }
 
 func TestDecodeAll(t *testing.T) {
-	// mpqDir specifies the path to an extracted "diabdat.mpq".
-	mpqDir := "diabdat/"
-
-	// Skip test if extracted "diabdat.mpq" is not present.
-	if !osutil.Exists(mpqDir) {
-		t.Skipf("%q directory not present", mpqDir)
-		return
-	}
+	// Skip test if "diabdat.mpq" is not present.
+	fsys := diabdat.FS(t)
 
 	golden := []struct {
 		// Relative CEL paths to "diabdat.mpq".
@@ -92,7 +86,6 @@ func TestDecodeAll(t *testing.T) {
 
 		// Decode CEL frames for each of the given palettes and compare against
 		// the expected SHA1 hashsums.
-		celPath := filepath.Join(mpqDir, g.relCelPath)
 		for _, palTest := range g.palTests {
 			// TODO: Remove sanity check once the test cases have matured.
 			wants := palTest.wants
@@ -102,13 +95,12 @@ func TestDecodeAll(t *testing.T) {
 			}
 
 			// Decode CEL frames for the given palette.
-			palPath := filepath.Join(mpqDir, palTest.relPalPath)
-			pal, err := cel.ParsePal(palPath)
+			pal, err := cel.ParsePalFS(fsys, palTest.relPalPath)
 			if err != nil {
 				t.Errorf("%q: unable to parse palette %q; %v", g.relCelPath, palTest.relPalPath, err)
 				continue
 			}
-			imgs, err := cel.DecodeAll(celPath, pal)
+			imgs, err := cel.DecodeAllFS(fsys, g.relCelPath, pal)
 			if err != nil {
 				t.Errorf("%q: unable to decode CEL frames; %v", g.relCelPath, err)
 				continue
@@ -17,10 +17,30 @@ func Get(name string) (*Config, error) {
 	if !ok {
 		return nil, errors.Errorf("unable to locate CEL config for %q", name)
 	}
-	conf.GetDecoderType = func(frameNum int) int {
+	// Return a deep copy, as the config data is shared between callers.
+	c := *conf
+	c.FrameWidth = copyMap(conf.FrameWidth)
+	c.FrameHeight = copyMap(conf.FrameHeight)
+	c.FrameAnchorX = copyMap(conf.FrameAnchorX)
+	c.FrameAnchorY = copyMap(conf.FrameAnchorY)
+	c.Pals = append([]string(nil), conf.Pals...)
+	c.Trns = append([]string(nil), conf.Trns...)
+	c.GetDecoderType = func(frameNum int) int {
 		return getDecoderType(name, frameNum)
 	}
-	return conf, nil
+	return &c, nil
+}
+
+// copyMap returns a copy of the given frame specific map, or nil if m is nil.
+func copyMap(m map[int]int) map[int]int {
+	if m == nil {
+		return nil
+	}
+	c := make(map[int]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
 }
 
 // A Config specifies the data required for decoding a given CEL image.
@@ -38,6 +58,15 @@ type Config struct {
 	Pals []string
 	// Colour transition paths.
 	Trns []string
+	// Default anchor offset of sprite frames, relative to the default placement
+	// of frames on the floor tile the sprite is placed on; which centers each
+	// frame horizontally on the tile, and aligns the bottom edges of the frame
+	// and the tile.
+	AnchorX, AnchorY int
+	// Specific frame anchor offsets, mapping from frame number to x or y
+	// offset relative to the default placement; for animations with displaced
+	// frames.
+	FrameAnchorX, FrameAnchorY map[int]int
 	// GetDecoderType returns the CEL frame decoder type of the given frame
 	// number. The decoder type may be one of the following.
 	//
@@ -90,6 +119,8 @@ type Config struct {
 // NOTE: The frames of the attack animation of unravelers (i.e.
 // "monsters/unrav/unrava.cl2") are displaced.
 
+// TODO: Add frame anchor offsets of the displaced animations noted above.
+
 // NOTE: The death animation of zombies (i.e. "monsters/zombie/zombied.cl2")
 // have strange camera angles, thus not covering each direction correctly.
 
@@ -4504,6 +4535,8 @@ var confs = map[string]*Config{
 		// NOTE: Each frame contain 128x128 (16384) pixels, but the correct width
 		// is 96.
 		H: 171, // h = npixels/w = 16384/96 = 170.66
+		// The engine centers the sprite based on the frame width 128.
+		AnchorX: -(128 - 96) / 2, // ref: 0x497E08
 		Trns: []string{
 			"monsters/monsters/general.trn", // ref: 0x49B6F8; Madburner, Warpfire Hellspawn
 			"monsters/monsters/wftd.trn",    // ref: 0x49B6F8; Wrathfire the Doomed
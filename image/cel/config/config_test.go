@@ -1492,3 +1492,53 @@ var npixelsMapping = map[string][]int{
 	"plrgfx/warrior/wmu/wmust.cl2":  {9216},
 	"plrgfx/warrior/wmu/wmuwl.cl2":  {9216},
 }
+
+func TestAnchor(t *testing.T) {
+	golden := []struct {
+		name  string
+		wantX int
+	}{
+		{name: "unrava.cl2", wantX: 0},
+		{name: "firema.cl2", wantX: -16}, // 96 pixels wide, centered as 128
+	}
+	for _, g := range golden {
+		conf, err := Get(g.name)
+		if err != nil {
+			t.Errorf("%q: unable to get config; %v", g.name, err)
+			continue
+		}
+		if conf.AnchorX != g.wantX {
+			t.Errorf("%q: anchor x offset mismatch; expected %d, got %d", g.name, g.wantX, conf.AnchorX)
+		}
+		// Modifications of the returned config are not visible to other
+		// callers.
+		conf.AnchorX++
+		again, err := Get(g.name)
+		if err != nil {
+			t.Errorf("%q: unable to get config; %v", g.name, err)
+			continue
+		}
+		if again.AnchorX != g.wantX {
+			t.Errorf("%q: anchor x offset mismatch after modification; expected %d, got %d", g.name, g.wantX, again.AnchorX)
+		}
+	}
+	// Modifications of the frame specific maps of the returned config are not
+	// visible to other callers either.
+	const name = "charbut.cel"
+	conf, err := Get(name)
+	if err != nil {
+		t.Fatalf("%q: unable to get config; %v", name, err)
+	}
+	conf.FrameWidth[0]++
+	conf.FrameAnchorX = map[int]int{0: 1}
+	again, err := Get(name)
+	if err != nil {
+		t.Fatalf("%q: unable to get config; %v", name, err)
+	}
+	if want, got := 95, again.FrameWidth[0]; got != want {
+		t.Errorf("%q: frame 0 width mismatch after modification; expected %d, got %d", name, want, got)
+	}
+	if again.FrameAnchorX != nil {
+		t.Errorf("%q: frame anchor x offsets mismatch after modification; expected nil, got %v", name, again.FrameAnchorX)
+	}
+}
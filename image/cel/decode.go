@@ -2,15 +2,16 @@ package cel
 
 import (
 	"fmt"
-	"image"
 	"image/color"
 	"image/draw"
 
 	"github.com/sanctuary/formats/image/cel/config"
 )
 
-// decoders maps CEL frame types to decoder functions.
-var decoders = [...]func(data []byte, w, h int, pal color.Palette) image.Image{
+// decoders maps CEL frame types to decoder functions. Each decoder draws the
+// decoded pixels using the given pixel drawer, and returns the number of bytes
+// of pixel data consumed.
+var decoders = [...]func(d *pixelDrawer, data []byte, pal color.Palette) int{
 	0: decodeType0,
 	1: decodeType1,
 	2: decodeType2,
@@ -22,15 +23,15 @@ var decoders = [...]func(data []byte, w, h int, pal color.Palette) image.Image{
 
 // getDecoder returns the CEL frame decoder of the given image config and frame
 // number.
-func getDecoder(conf *config.Config, frameNum int) func(data []byte, w, h int, pal color.Palette) image.Image {
+func getDecoder(conf *config.Config, frameNum int) func(d *pixelDrawer, data []byte, pal color.Palette) int {
 	return decoders[conf.GetDecoderType(frameNum)]
 }
 
 // levelFrameWidth specifies the frame width of level CELs.
 const levelFrameWidth = 32
 
-// decodeType0 decodes the pixel data of a type 0 CEL frame of the specified
-// dimensions, using colours from the provided palette.
+// decodeType0 decodes the pixel data of a type 0 CEL frame using colours from
+// the provided palette, and returns the number of bytes consumed.
 //
 // A type 0 CEL frame corresponds to an unencoded 32x32 image without
 // transparency, having pixel data arranged as follows, where 'x' represents an
@@ -71,23 +72,22 @@ const levelFrameWidth = 32
 //    |xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx|
 //    +--------------------------------+
 //
-func decodeType0(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
-	for _, b := range data {
-		drawPixel(pal[b])
+func decodeType0(d *pixelDrawer, data []byte, pal color.Palette) int {
+	pos := 0
+	for pos < len(data) && !d.full() {
+		d.drawPixel(pal[data[pos]])
+		pos++
 	}
-	return img
+	return pos
 }
 
 // TODO: Add high-level description of how type 1 pixel data is encoded.
 
-// decodeType1 decodes the pixel data of a regular (type 1) CEL frame of the
-// specified dimensions, using colours from the provided palette.
-func decodeType1(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
-	for pos := 0; pos < len(data); {
+// decodeType1 decodes the pixel data of a regular (type 1) CEL frame using
+// colours from the provided palette, and returns the number of bytes consumed.
+func decodeType1(d *pixelDrawer, data []byte, pal color.Palette) int {
+	pos := 0
+	for pos < len(data) && !d.full() {
 		n := int(int8(data[pos]))
 		pos++
 		switch {
@@ -95,21 +95,21 @@ func decodeType1(data []byte, w, h int, pal color.Palette) image.Image {
 			// Transparent pixels.
 			n = -n
 			for i := 0; i < n; i++ {
-				drawPixel(color.Transparent)
+				d.drawPixel(color.Transparent)
 			}
 		default:
 			// Regular pixels.
 			for i := 0; i < n; i++ {
-				drawPixel(pal[data[pos]])
+				d.drawPixel(pal[data[pos]])
 				pos++
 			}
 		}
 	}
-	return img
+	return pos
 }
 
-// decodeType2 decodes the pixel data of a type 2 CEL frame of the specified
-// dimensions, using colours from the provided palette.
+// decodeType2 decodes the pixel data of a type 2 CEL frame using colours from
+// the provided palette, and returns the number of bytes consumed.
 //
 // A type 2 CEL frame corresponds to a 32x32 image of a left-facing triangle,
 // having pixel data arranged as follows, where 'x' represents an explicit
@@ -153,9 +153,7 @@ func decodeType1(data []byte, w, h int, pal color.Palette) image.Image {
 //    |                                |
 //    +--------------------------------+
 //
-func decodeType2(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
+func decodeType2(d *pixelDrawer, data []byte, pal color.Palette) int {
 	ns := []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32, 30, 28, 26, 24, 22, 20, 18, 16, 14, 12, 10, 8, 6, 4, 2, 0}
 	pos := 0
 	for i, n := range ns {
@@ -170,19 +168,19 @@ func decodeType2(data []byte, w, h int, pal color.Palette) image.Image {
 		}
 		// Transparent pixels.
 		for j := n; j < levelFrameWidth; j++ {
-			drawPixel(color.Transparent)
+			d.drawPixel(color.Transparent)
 		}
 		// Regular pixels.
 		for j := 0; j < n; j++ {
-			drawPixel(pal[data[pos]])
+			d.drawPixel(pal[data[pos]])
 			pos++
 		}
 	}
-	return img
+	return pos
 }
 
-// decodeType3 decodes the pixel data of a type 3 CEL frame of the specified
-// dimensions, using colours from the provided palette.
+// decodeType3 decodes the pixel data of a type 3 CEL frame using colours from
+// the provided palette, and returns the number of bytes consumed.
 //
 // A type 3 CEL frame corresponds to a 32x32 image of a right-facing triangle,
 // having pixel data arranged as follows, where 'x' represents an explicit
@@ -226,15 +224,13 @@ func decodeType2(data []byte, w, h int, pal color.Palette) image.Image {
 //    |                                |
 //    +--------------------------------+
 //
-func decodeType3(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
+func decodeType3(d *pixelDrawer, data []byte, pal color.Palette) int {
 	ns := []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32, 30, 28, 26, 24, 22, 20, 18, 16, 14, 12, 10, 8, 6, 4, 2, 0}
 	pos := 0
 	for i, n := range ns {
 		// Regular pixels.
 		for j := 0; j < n; j++ {
-			drawPixel(pal[data[pos]])
+			d.drawPixel(pal[data[pos]])
 			pos++
 		}
 		// Even lines end with two explicit transparent pixels.
@@ -248,14 +244,14 @@ func decodeType3(data []byte, w, h int, pal color.Palette) image.Image {
 		}
 		// Transparent pixels.
 		for j := n; j < levelFrameWidth; j++ {
-			drawPixel(color.Transparent)
+			d.drawPixel(color.Transparent)
 		}
 	}
-	return img
+	return pos
 }
 
-// decodeType4 decodes the pixel data of a type 4 CEL frame of the specified
-// dimensions, using colours from the provided palette.
+// decodeType4 decodes the pixel data of a type 4 CEL frame using colours from
+// the provided palette, and returns the number of bytes consumed.
 //
 // A type 4 CEL frame corresponds to a 32x32 image of a right-facing trapezoid,
 // having pixel data arranged as follows, where 'x' represents an explicit
@@ -299,9 +295,7 @@ func decodeType3(data []byte, w, h int, pal color.Palette) image.Image {
 //    |xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx|
 //    +--------------------------------+
 //
-func decodeType4(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
+func decodeType4(d *pixelDrawer, data []byte, pal color.Palette) int {
 	ns := []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32}
 	pos := 0
 	for i, n := range ns {
@@ -316,24 +310,24 @@ func decodeType4(data []byte, w, h int, pal color.Palette) image.Image {
 		}
 		// Transparent pixels.
 		for j := n; j < levelFrameWidth; j++ {
-			drawPixel(color.Transparent)
+			d.drawPixel(color.Transparent)
 		}
 		// Regular pixels.
 		for j := 0; j < n; j++ {
-			drawPixel(pal[data[pos]])
+			d.drawPixel(pal[data[pos]])
 			pos++
 		}
 	}
 	// Regular pixels.
-	for _, b := range data[pos:] {
-		drawPixel(pal[b])
+	for pos < len(data) && !d.full() {
+		d.drawPixel(pal[data[pos]])
 		pos++
 	}
-	return img
+	return pos
 }
 
-// decodeType5 decodes the pixel data of a type 5 CEL frame of the specified
-// dimensions, using colours from the provided palette.
+// decodeType5 decodes the pixel data of a type 5 CEL frame using colours from
+// the provided palette, and returns the number of bytes consumed.
 //
 // A type 5 CEL frame corresponds to a 32x32 image of a left-facing trapezoid,
 // having pixel data arranged as follows, where 'x' represents an explicit
@@ -377,15 +371,13 @@ func decodeType4(data []byte, w, h int, pal color.Palette) image.Image {
 //    |xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx|
 //    +--------------------------------+
 //
-func decodeType5(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
+func decodeType5(d *pixelDrawer, data []byte, pal color.Palette) int {
 	ns := []int{2, 4, 6, 8, 10, 12, 14, 16, 18, 20, 22, 24, 26, 28, 30, 32}
 	pos := 0
 	for i, n := range ns {
 		// Regular pixels.
 		for j := 0; j < n; j++ {
-			drawPixel(pal[data[pos]])
+			d.drawPixel(pal[data[pos]])
 			pos++
 		}
 		// Even lines end with two explicit transparent pixels.
@@ -399,25 +391,24 @@ func decodeType5(data []byte, w, h int, pal color.Palette) image.Image {
 		}
 		// Transparent pixels.
 		for j := n; j < levelFrameWidth; j++ {
-			drawPixel(color.Transparent)
+			d.drawPixel(color.Transparent)
 		}
 	}
 	// Regular pixels.
-	for _, b := range data[pos:] {
-		drawPixel(pal[b])
+	for pos < len(data) && !d.full() {
+		d.drawPixel(pal[data[pos]])
 		pos++
 	}
-	return img
+	return pos
 }
 
 // TODO: Add high-level description of how type 6 pixel data is encoded.
 
-// decodeType6 decodes the pixel data of a regular (type 6) CL2 frame of the
-// specified dimensions, using colours from the provided palette.
-func decodeType6(data []byte, w, h int, pal color.Palette) image.Image {
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	drawPixel := pixelDrawer(img, w, h)
-	for pos := 0; pos < len(data); {
+// decodeType6 decodes the pixel data of a regular (type 6) CL2 frame using
+// colours from the provided palette, and returns the number of bytes consumed.
+func decodeType6(d *pixelDrawer, data []byte, pal color.Palette) int {
+	pos := 0
+	for pos < len(data) && !d.full() {
 		n := int(int8(data[pos]))
 		pos++
 		switch {
@@ -426,44 +417,69 @@ func decodeType6(data []byte, w, h int, pal color.Palette) image.Image {
 			n = -n - 65
 			c := pal[data[pos]]
 			for i := 0; i < n; i++ {
-				drawPixel(c)
+				d.drawPixel(c)
 			}
 			pos++
 		case n < 0:
 			// Regular pixels.
 			n = -n
 			for i := 0; i < n; i++ {
-				drawPixel(pal[data[pos]])
+				d.drawPixel(pal[data[pos]])
 				pos++
 			}
 		default:
 			// Transparent pixels.
 			for i := 0; i < n; i++ {
-				drawPixel(color.Transparent)
+				d.drawPixel(color.Transparent)
 			}
 		}
 	}
-	return img
+	return pos
 }
 
-// pixelDrawer returns a function which may be invoked to incrementally set
-// pixels; starting in the lower left corner, going from left to right, and then
-// row by row from the bottom to the top of the image.
-func pixelDrawer(dst draw.Image, w, h int) func(color.Color) {
-	x, y := 0, h-1
-	return func(c color.Color) {
-		// TODO: Remove sanity check once the cel decoder library has mature.
-		if x < 0 || x >= w {
-			panic(fmt.Sprintf("cel.pixelDrawer.drawPixel: invalid x; expected 0 <= x < %d, got x=%d", w, x))
-		}
-		if y < 0 || y >= h {
-			panic(fmt.Sprintf("cel.pixelDrawer.drawPixel: invalid y; expected 0 <= y < %d, got y=%d", h, y))
-		}
-		dst.Set(x, y, c)
-		x++
-		if x >= w {
-			x = 0
-			y--
-		}
+// A pixelDrawer incrementally sets pixels of an image; starting in the lower
+// left corner, going from left to right, and then row by row from the bottom to
+// the top of the image.
+type pixelDrawer struct {
+	// Destination image.
+	dst draw.Image
+	// Image dimensions.
+	w, h int
+	// Position of the next pixel.
+	x, y int
+	// Number of pixels drawn; including pixels beyond the end of the image,
+	// which are counted but not drawn.
+	n int
+}
+
+// newPixelDrawer returns a new pixel drawer for the given destination image of
+// the specified dimensions.
+func newPixelDrawer(dst draw.Image, w, h int) *pixelDrawer {
+	return &pixelDrawer{dst: dst, w: w, h: h, y: h - 1}
+}
+
+// full reports whether every pixel of the image has been drawn.
+func (d *pixelDrawer) full() bool {
+	return d.n >= d.w*d.h
+}
+
+// drawPixel sets the next pixel of the image to the given colour. Pixels
+// beyond the end of the image are counted, so that over-draw is reported by
+// the pixel count, but not drawn.
+func (d *pixelDrawer) drawPixel(c color.Color) {
+	if d.full() {
+		d.n++
+		return
+	}
+	// TODO: Remove sanity check once the cel decoder library has mature.
+	if d.x < 0 || d.x >= d.w {
+		panic(fmt.Sprintf("cel.pixelDrawer.drawPixel: invalid x; expected 0 <= x < %d, got x=%d", d.w, d.x))
+	}
+	d.dst.Set(d.x, d.y, c)
+	d.n++
+	d.x++
+	if d.x >= d.w {
+		d.x = 0
+		d.y--
 	}
 }
@@ -0,0 +1,104 @@
+package cel
+
+import (
+	"image"
+	"image/color"
+)
+
+// An IndexedImage is a decoded CEL frame which retains the palette index of
+// each pixel, as required to shade the frame using light tables. Transparent
+// pixels are tracked by a separate mask, as every index of the 256 colour
+// palettes of the game is a regular colour.
+//
+// Frames decoded using colour transition tables (see TransitionTable.Pal) store
+// the palette indices of the source palette; i.e. the indices after colour
+// transition.
+type IndexedImage struct {
+	// Palette indices of the pixels, and colours of the palette indices.
+	*image.Paletted
+	// Mask of the opaque pixels; with alpha 0xFF for opaque and 0 for
+	// transparent pixels.
+	Mask *image.Alpha
+}
+
+// NewIndexedImage returns a new fully transparent indexed image with the given
+// bounds and palette.
+func NewIndexedImage(r image.Rectangle, pal color.Palette) *IndexedImage {
+	return &IndexedImage{
+		Paletted: image.NewPaletted(r, pal),
+		Mask:     image.NewAlpha(r),
+	}
+}
+
+// ColorModel returns the colour model of the image.
+func (img *IndexedImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// At returns the colour of the pixel at (x, y).
+func (img *IndexedImage) At(x, y int) color.Color {
+	if img.Mask.AlphaAt(x, y).A == 0 {
+		return color.RGBA{}
+	}
+	return img.Paletted.At(x, y)
+}
+
+// Set sets the colour of the pixel at (x, y). The palette index of colours of
+// the palette passed to the CEL decoder is stored as is, and other colours are
+// approximated by the closest colour of the palette.
+func (img *IndexedImage) Set(x, y int, c color.Color) {
+	if !(image.Point{X: x, Y: y}.In(img.Rect)) {
+		return
+	}
+	switch c := c.(type) {
+	case palColor:
+		img.Paletted.SetColorIndex(x, y, c.index)
+	default:
+		if _, _, _, a := c.RGBA(); a == 0 {
+			img.Paletted.SetColorIndex(x, y, 0)
+			img.Mask.SetAlpha(x, y, color.Alpha{})
+			return
+		}
+		img.Paletted.Set(x, y, c)
+	}
+	img.Mask.SetAlpha(x, y, color.Alpha{A: 0xFF})
+}
+
+// IndexAt returns the palette index of the pixel at (x, y), and reports whether
+// the pixel is opaque.
+func (img *IndexedImage) IndexAt(x, y int) (index uint8, opaque bool) {
+	if img.Mask.AlphaAt(x, y).A == 0 {
+		return 0, false
+	}
+	return img.Paletted.ColorIndexAt(x, y), true
+}
+
+// A palColor is a palette colour which records its index into the source
+// palette, so that decoded frames retain the palette indices of their pixels.
+type palColor struct {
+	color.Color
+	// Index into the source palette.
+	index uint8
+}
+
+// indexPal returns the palette used by the CEL decoders to decode frames into
+// an IndexedImage, and the colours of the source palette indices, as stored in
+// the IndexedImage.
+func indexPal(pal color.Palette) (decodePal, srcPal color.Palette) {
+	decodePal = make(color.Palette, len(pal))
+	srcPal = make(color.Palette, len(pal))
+	for i := range srcPal {
+		srcPal[i] = color.RGBA{A: 0xFF}
+	}
+	for i, c := range pal {
+		pc, ok := c.(palColor)
+		if !ok {
+			pc = palColor{Color: c, index: uint8(i)}
+		}
+		decodePal[i] = pc
+		if int(pc.index) < len(srcPal) {
+			srcPal[pc.index] = pc.Color
+		}
+	}
+	return decodePal, srcPal
+}
@@ -0,0 +1,50 @@
+package cel
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestIndexedImage(t *testing.T) {
+	// Palette with duplicate colours.
+	pal := make(color.Palette, 256)
+	for i := range pal {
+		pal[i] = color.RGBA{R: uint8(i / 2), A: 0xFF}
+	}
+	// Colour transition table swapping index 2 and 3.
+	trn := &TransitionTable{}
+	for i := range trn.Indices {
+		trn.Indices[i] = uint8(i)
+	}
+	trn.Indices[2], trn.Indices[3] = 3, 2
+	// Type 1 frame of 2 regular pixels, followed by 2 transparent pixels.
+	data := []byte{0x02, 0x02, 0x03, 0xFE}
+	golden := []struct {
+		pal color.Palette
+		// Expected palette indices of the first two pixels.
+		want [2]uint8
+	}{
+		{pal: pal, want: [2]uint8{2, 3}},
+		{pal: trn.Pal(pal), want: [2]uint8{3, 2}},
+	}
+	for _, g := range golden {
+		decodePal, srcPal := indexPal(g.pal)
+		img := NewIndexedImage(image.Rect(0, 0, 4, 1), srcPal)
+		decodeType1(newPixelDrawer(img, 4, 1), data, decodePal)
+		for x, want := range g.want {
+			got, ok := img.IndexAt(x, 0)
+			if !ok || got != want {
+				t.Errorf("pixel %d: palette index mismatch; expected %d, got %d (opaque %v)", x, want, got, ok)
+			}
+			if c := img.At(x, 0); c != pal[want] {
+				t.Errorf("pixel %d: colour mismatch; expected %v, got %v", x, pal[want], c)
+			}
+		}
+		for x := 2; x < 4; x++ {
+			if _, ok := img.IndexAt(x, 0); ok {
+				t.Errorf("pixel %d: expected transparent pixel", x)
+			}
+		}
+	}
+}
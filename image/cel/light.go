@@ -0,0 +1,123 @@
+package cel
+
+// NLightLevels specifies the number of light levels of the game; from fully lit
+// (light level 0) to black (light level 15).
+const NLightLevels = 16
+
+// LightTables returns the light tables of the game, one colour transition table
+// per light level, which map from palette index to the palette index of the
+// colour shaded with the given light level. The hell flag specifies whether to
+// use the light tables of hell, which shade the red colours of the palette
+// (indices 1-31) differently.
+//
+// The colours of a palette are arranged in groups of 8 or 16 colours, ordered
+// from bright to dark; and shading advances the colours within each group.
+//
+// ref: MakeLightTable
+func LightTables(hell bool) []*TransitionTable {
+	const lights = NLightLevels - 1
+	tables := make([]*TransitionTable, NLightLevels)
+	for i := range tables {
+		tables[i] = &TransitionTable{}
+	}
+	for shade := 0; shade < lights; shade++ {
+		tbl := &tableWriter{t: tables[shade]}
+		tbl.put(0)
+		for j := 0; j < 8; j++ {
+			col, max := 16*j+shade, 16*j+15
+			for k := 0; k < 16; k++ {
+				if k != 0 || j != 0 {
+					tbl.put(col)
+				}
+				if col < max {
+					col++
+				} else {
+					col, max = 0, 0
+				}
+			}
+		}
+		for j := 16; j < 20; j++ {
+			col, max := 8*j+(shade>>1), 8*j+7
+			for k := 0; k < 8; k++ {
+				tbl.put(col)
+				if col < max {
+					col++
+				} else {
+					col, max = 0, 0
+				}
+			}
+		}
+		for j := 10; j < 16; j++ {
+			col, max := 16*j+shade, 16*j+15
+			for k := 0; k < 16; k++ {
+				tbl.put(col)
+				if col < max {
+					col++
+				} else {
+					col, max = 0, 0
+				}
+				if col == 255 {
+					col, max = 0, 0
+				}
+			}
+		}
+	}
+	// The last light table maps every colour to black (index 0).
+	if !hell {
+		return tables
+	}
+
+	// Shade the blood colours of hell.
+	for i := 0; i < lights; i++ {
+		l1 := lights - i
+		l2 := l1
+		div := lights / l1
+		rem := lights % l1
+		var blood [16]uint8
+		col, cnt := uint8(1), 0
+		for j := 1; j < 16; j++ {
+			blood[j] = col
+			l2 += rem
+			if l2 > l1 && j < 15 {
+				j++
+				blood[j] = col
+				l2 -= l1
+			}
+			cnt++
+			if cnt == div {
+				col++
+				cnt = 0
+			}
+		}
+		tbl := &tableWriter{t: tables[i]}
+		tbl.put(0)
+		for j := 1; j <= 15; j++ {
+			tbl.put(int(blood[j]))
+		}
+		for j := 15; j > 0; j-- {
+			tbl.put(int(blood[j]))
+		}
+		tbl.put(1)
+	}
+	tbl := &tableWriter{t: tables[lights]}
+	tbl.put(0)
+	for j := 0; j < 31; j++ {
+		tbl.put(1)
+	}
+	return tables
+}
+
+// A tableWriter writes the indices of a colour transition table in order. The
+// remaining indices are left unchanged.
+type tableWriter struct {
+	// Colour transition table.
+	t *TransitionTable
+	// Index of the next write.
+	n int
+}
+
+// put writes the given palette index to the colour transition table.
+func (w *tableWriter) put(v int) {
+	w.t.Indices[w.n] = uint8(v)
+	w.n++
+}
@@ -0,0 +1,42 @@
+package cel_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/formats/image/cel"
+)
+
+func TestLightTables(t *testing.T) {
+	golden := []struct {
+		hell  bool
+		light int
+		// Palette index before and after shading.
+		in, want uint8
+	}{
+		// Fully lit.
+		{light: 0, in: 0, want: 0},
+		{light: 0, in: 17, want: 17},
+		// Shading advances the colours within groups of 16 colours.
+		{light: 1, in: 16, want: 17},
+		{light: 5, in: 30, want: 0},
+		// Groups of 8 colours are shaded at half the rate.
+		{light: 4, in: 128, want: 130},
+		// Colour 255 is shaded to black.
+		{light: 1, in: 254, want: 0},
+		// Black.
+		{light: 15, in: 100, want: 0},
+		// Blood colours of hell.
+		{hell: true, light: 15, in: 10, want: 1},
+		{hell: true, light: 15, in: 100, want: 0},
+		{hell: true, light: 1, in: 100, want: 101},
+	}
+	for _, g := range golden {
+		tables := cel.LightTables(g.hell)
+		if got, want := len(tables), cel.NLightLevels; got != want {
+			t.Fatalf("light table count mismatch; expected %d, got %d", want, got)
+		}
+		if got := tables[g.light].Indices[g.in]; got != g.want {
+			t.Errorf("palette index mismatch of index %d at light level %d (hell=%v); expected %d, got %d", g.in, g.light, g.hell, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package monsters
+
+// Monster graphics.
+var (
+	acid     = &Gfx{Dir: "monsters/acid", Prefix: "acid"}
+	bat      = &Gfx{Dir: "monsters/bat", Prefix: "bat"}
+	bigfall  = &Gfx{Dir: "monsters/bigfall", Prefix: "fallg"}
+	black    = &Gfx{Dir: "monsters/black", Prefix: "black"}
+	darkmage = &Gfx{Dir: "monsters/darkmage", Prefix: "dmage"}
+	demskel  = &Gfx{Dir: "monsters/demskel", Prefix: "demskl"}
+	diablo   = &Gfx{Dir: "monsters/diablo", Prefix: "diablo"}
+	falspear = &Gfx{Dir: "monsters/falspear", Prefix: "phall"}
+	falsword = &Gfx{Dir: "monsters/falsword", Prefix: "fall"}
+	fat      = &Gfx{Dir: "monsters/fat", Prefix: "fat"}
+	fatc     = &Gfx{Dir: "monsters/fatc", Prefix: "fatc"}
+	fireman  = &Gfx{Dir: "monsters/fireman", Prefix: "firem"}
+	gargoyle = &Gfx{Dir: "monsters/gargoyle", Prefix: "gargo"}
+	goatbow  = &Gfx{Dir: "monsters/goatbow", Prefix: "goatb"}
+	goatlord = &Gfx{Dir: "monsters/goatlord", Prefix: "goatl"}
+	goatmace = &Gfx{Dir: "monsters/goatmace", Prefix: "goat"}
+	golem    = &Gfx{Dir: "monsters/golem", Prefix: "golem"}
+	mage     = &Gfx{Dir: "monsters/mage", Prefix: "mage"}
+	magma    = &Gfx{Dir: "monsters/magma", Prefix: "magma"}
+	mega     = &Gfx{Dir: "monsters/mega", Prefix: "mega"}
+	rhino    = &Gfx{Dir: "monsters/rhino", Prefix: "rhino"}
+	scav     = &Gfx{Dir: "monsters/scav", Prefix: "scav"}
+	skelaxe  = &Gfx{Dir: "monsters/skelaxe", Prefix: "sklax"}
+	skelbow  = &Gfx{Dir: "monsters/skelbow", Prefix: "sklbw"}
+	skelsd   = &Gfx{Dir: "monsters/skelsd", Prefix: "sklsr"}
+	sking    = &Gfx{Dir: "monsters/sking", Prefix: "sking"}
+	snake    = &Gfx{Dir: "monsters/snake", Prefix: "snake"}
+	sneak    = &Gfx{Dir: "monsters/sneak", Prefix: "sneak"}
+	succ     = &Gfx{Dir: "monsters/succ", Prefix: "scbs"}
+	thin     = &Gfx{Dir: "monsters/thin", Prefix: "thin"}
+	tsneak   = &Gfx{Dir: "monsters/tsneak", Prefix: "tsneak"}
+	unrav    = &Gfx{Dir: "monsters/unrav", Prefix: "unrav"}
+	zombie   = &Gfx{Dir: "monsters/zombie", Prefix: "zombie"}
+)
+
+// NOTE: The wyrm monster types (Wyrm, Cave Slug, Devil Wyrm and Devourer) are
+// omitted, as their graphics ("monsters/worm/worm%c.cl2") are not present in
+// "diabdat.mpq".
+
+// Types lists the monster types of the game (ref: 0x497E08), in the order of
+// the monster data table. Monster types sharing the same name are told apart
+// by the weapon they wield.
+var Types = []*Monster{
+	{Name: "Zombie", Gfx: zombie},
+	{Name: "Ghoul", Gfx: zombie, Trn: "monsters/zombie/bluered.trn"},
+	{Name: "Rotting Carcass", Gfx: zombie, Trn: "monsters/zombie/grey.trn"},
+	{Name: "Black Death", Gfx: zombie, Trn: "monsters/zombie/yellow.trn"},
+	{Name: "Fallen One (spear)", Gfx: falspear, Trn: "monsters/falspear/fallent.trn"},
+	{Name: "Carver (spear)", Gfx: falspear, Trn: "monsters/falspear/dark.trn"},
+	{Name: "Devil Kin (spear)", Gfx: falspear},
+	{Name: "Dark One (spear)", Gfx: falspear, Trn: "monsters/falspear/blue.trn"},
+	{Name: "Skeleton", Gfx: skelaxe, Trn: "monsters/skelaxe/white.trn"},
+	{Name: "Corpse Axe", Gfx: skelaxe, Trn: "monsters/skelaxe/skelt.trn"},
+	{Name: "Burning Dead", Gfx: skelaxe},
+	{Name: "Horror", Gfx: skelaxe, Trn: "monsters/skelaxe/black.trn"},
+	{Name: "Fallen One (sword)", Gfx: falsword, Trn: "monsters/falsword/fallent.trn"},
+	{Name: "Carver (sword)", Gfx: falsword, Trn: "monsters/falsword/dark.trn"},
+	{Name: "Devil Kin (sword)", Gfx: falsword},
+	{Name: "Dark One (sword)", Gfx: falsword, Trn: "monsters/falsword/blue.trn"},
+	{Name: "Scavenger", Gfx: scav},
+	{Name: "Plague Eater", Gfx: scav, Trn: "monsters/scav/scavbr.trn"},
+	{Name: "Shadow Beast", Gfx: scav, Trn: "monsters/scav/scavbe.trn"},
+	{Name: "Bone Gasher", Gfx: scav, Trn: "monsters/scav/scavw.trn"},
+	{Name: "Skeleton Archer", Gfx: skelbow, Trn: "monsters/skelbow/white.trn"},
+	{Name: "Corpse Bow", Gfx: skelbow, Trn: "monsters/skelbow/skelt.trn"},
+	{Name: "Burning Dead Archer", Gfx: skelbow},
+	{Name: "Horror Archer", Gfx: skelbow, Trn: "monsters/skelbow/black.trn"},
+	{Name: "Skeleton Captain", Gfx: skelsd, Trn: "monsters/skelsd/white.trn"},
+	{Name: "Corpse Captain", Gfx: skelsd, Trn: "monsters/skelsd/skelt.trn"},
+	{Name: "Burning Dead Captain", Gfx: skelsd},
+	{Name: "Horror Captain", Gfx: skelsd, Trn: "monsters/skelsd/black.trn"},
+	{Name: "Invisible Lord", Gfx: tsneak},
+	{Name: "Hidden", Gfx: sneak},
+	{Name: "Stalker", Gfx: sneak, Trn: "monsters/sneak/sneakv2.trn"},
+	{Name: "Unseen", Gfx: sneak, Trn: "monsters/sneak/sneakv3.trn"},
+	{Name: "Illusion Weaver", Gfx: sneak, Trn: "monsters/sneak/sneakv1.trn"},
+	{Name: "Lord Sayter", Gfx: goatlord},
+	{Name: "Flesh Clan (mace)", Gfx: goatmace},
+	{Name: "Stone Clan (mace)", Gfx: goatmace, Trn: "monsters/goatmace/beige.trn"},
+	{Name: "Fire Clan (mace)", Gfx: goatmace, Trn: "monsters/goatmace/red.trn"},
+	{Name: "Night Clan (mace)", Gfx: goatmace, Trn: "monsters/goatmace/gray.trn"},
+	{Name: "Fiend", Gfx: bat},
+	{Name: "Blink", Gfx: bat, Trn: "monsters/bat/red.trn"},
+	{Name: "Gloom", Gfx: bat, Trn: "monsters/bat/grey.trn"},
+	{Name: "Familiar", Gfx: bat, Trn: "monsters/bat/orange.trn"},
+	{Name: "Flesh Clan (bow)", Gfx: goatbow},
+	{Name: "Stone Clan (bow)", Gfx: goatbow, Trn: "monsters/goatbow/beige.trn"},
+	{Name: "Fire Clan (bow)", Gfx: goatbow, Trn: "monsters/goatbow/red.trn"},
+	{Name: "Night Clan (bow)", Gfx: goatbow, Trn: "monsters/goatbow/gray.trn"},
+	{Name: "Acid Beast", Gfx: acid},
+	{Name: "Poison Spitter", Gfx: acid, Trn: "monsters/acid/acidblk.trn"},
+	{Name: "Pit Beast", Gfx: acid, Trn: "monsters/acid/acidb.trn"},
+	{Name: "Lava Maw", Gfx: acid, Trn: "monsters/acid/acidr.trn"},
+	{Name: "Skeleton King", Gfx: sking, Trn: "monsters/skelaxe/white.trn"},
+	{Name: "The Butcher", Gfx: fatc},
+	{Name: "Overlord", Gfx: fat},
+	{Name: "Mud Man", Gfx: fat, Trn: "monsters/fat/blue.trn"},
+	{Name: "Toad Demon", Gfx: fat, Trn: "monsters/fat/fatb.trn"},
+	{Name: "Flayed One", Gfx: fat, Trn: "monsters/fat/fatf.trn"},
+	{Name: "Magma Demon", Gfx: magma},
+	{Name: "Blood Stone", Gfx: magma, Trn: "monsters/magma/yellow.trn"},
+	{Name: "Hell Stone", Gfx: magma, Trn: "monsters/magma/blue.trn"},
+	{Name: "Lava Lord", Gfx: magma, Trn: "monsters/magma/wierd.trn"},
+	{Name: "Horned Demon", Gfx: rhino},
+	{Name: "Mud Runner", Gfx: rhino, Trn: "monsters/rhino/orange.trn"},
+	{Name: "Frost Charger", Gfx: rhino, Trn: "monsters/rhino/blue.trn"},
+	{Name: "Obsidian Lord", Gfx: rhino, Trn: "monsters/rhino/rhinob.trn"},
+	{Name: "Bone Demon", Gfx: demskel},
+	{Name: "Red Death", Gfx: demskel},
+	{Name: "Litch Demon", Gfx: demskel},
+	{Name: "Undead Balrog", Gfx: demskel},
+	{Name: "Incinerator", Gfx: fireman},
+	{Name: "Flame Lord", Gfx: fireman},
+	{Name: "Doom Fire", Gfx: fireman},
+	{Name: "Hell Burner", Gfx: fireman},
+	{Name: "Red Storm", Gfx: thin, Trn: "monsters/thin/thinv3.trn"},
+	{Name: "Storm Rider", Gfx: thin},
+	{Name: "Storm Lord", Gfx: thin, Trn: "monsters/thin/thinv2.trn"},
+	{Name: "Maelstorm", Gfx: thin, Trn: "monsters/thin/thinv1.trn"},
+	{Name: "Devil Kin Brute", Gfx: bigfall},
+	{Name: "Winged-Demon", Gfx: gargoyle},
+	{Name: "Gargoyle", Gfx: gargoyle, Trn: "monsters/gargoyle/gare.trn"},
+	{Name: "Blood Claw", Gfx: gargoyle, Trn: "monsters/gargoyle/gargbr.trn"},
+	{Name: "Death Wing", Gfx: gargoyle, Trn: "monsters/gargoyle/gargb.trn"},
+	{Name: "Slayer", Gfx: mega},
+	{Name: "Guardian", Gfx: mega, Trn: "monsters/mega/guard.trn"},
+	{Name: "Vortex Lord", Gfx: mega, Trn: "monsters/mega/vtexl.trn"},
+	{Name: "Balrog", Gfx: mega, Trn: "monsters/mega/balr.trn"},
+	{Name: "Cave Viper", Gfx: snake},
+	{Name: "Fire Drake", Gfx: snake, Trn: "monsters/snake/snakr.trn"},
+	{Name: "Gold Viper", Gfx: snake, Trn: "monsters/snake/snakg.trn"},
+	{Name: "Azure Drake", Gfx: snake, Trn: "monsters/snake/snakb.trn"},
+	{Name: "Black Knight", Gfx: black},
+	{Name: "Doom Guard", Gfx: black, Trn: "monsters/black/blkkntrt.trn"},
+	{Name: "Steel Lord", Gfx: black, Trn: "monsters/black/blkkntbt.trn"},
+	{Name: "Blood Knight", Gfx: black, Trn: "monsters/black/blkkntbe.trn"},
+	{Name: "Unraveler", Gfx: unrav},
+	{Name: "Hollow One", Gfx: unrav},
+	{Name: "Pain Master", Gfx: unrav},
+	{Name: "Reality Weaver", Gfx: unrav},
+	{Name: "Succubus", Gfx: succ},
+	{Name: "Snow Witch", Gfx: succ, Trn: "monsters/succ/succb.trn"},
+	{Name: "Hell Spawn", Gfx: succ, Trn: "monsters/succ/succrw.trn"},
+	{Name: "Soul Burner", Gfx: succ, Trn: "monsters/succ/succbw.trn"},
+	{Name: "Counselor", Gfx: mage},
+	{Name: "Magistrate", Gfx: mage, Trn: "monsters/mage/cnselg.trn"},
+	{Name: "Cabalist", Gfx: mage, Trn: "monsters/mage/cnselgd.trn"},
+	{Name: "Advocate", Gfx: mage, Trn: "monsters/mage/cnselbk.trn"},
+	{Name: "Golem", Gfx: golem},
+	{Name: "The Dark Lord", Gfx: diablo},
+	{Name: "The Arch-Litch Malignus", Gfx: darkmage},
+}
+
+// Uniques lists the unique monsters of the game (ref: 0x49B6F8), sorted by
+// graphics directory and name.
+var Uniques = []*Monster{
+	{Name: "Chaoshowler", Gfx: acid, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Deathspit", Gfx: acid, Trn: "monsters/monsters/bfds.trn", Unique: true},
+	{Name: "Plaguewrath", Gfx: acid, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Foulwing", Gfx: bat, Trn: "monsters/monsters/db.trn", Unique: true},
+	{Name: "Moonbender", Gfx: bat, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Wrathraven", Gfx: bat, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Graywar the Slayer", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Lachdanan", Gfx: black, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Lionskull the Bent", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Rustweaver", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Sir Gorash", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Steelskull the Hunter", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Warlord of Blood", Gfx: black, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bongo", Gfx: falspear, Trn: "monsters/monsters/bng.trn", Unique: true},
+	{Name: "Pukerat the Unclean", Gfx: falspear, Trn: "monsters/monsters/ptu.trn", Unique: true},
+	{Name: "Snotspill", Gfx: falspear, Trn: "monsters/monsters/bng.trn", Unique: true},
+	{Name: "Bladeskin the Slasher", Gfx: falsword, Trn: "monsters/monsters/bsts.trn", Unique: true},
+	{Name: "Gutshank the Quick", Gfx: falsword, Trn: "monsters/monsters/gtq.trn", Unique: true},
+	{Name: "Shadowcrow", Gfx: falsword, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Baron Sludge", Gfx: fat, Trn: "monsters/monsters/bsm.trn", Unique: true},
+	{Name: "Bilefroth the Pit Master", Gfx: fat, Trn: "monsters/monsters/bftp.trn", Unique: true},
+	{Name: "Oozedrool", Gfx: fat, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "The Butcher", Gfx: fatc, Trn: "monsters/monsters/genrl.trn", Unique: true},
+	{Name: "Madburner", Gfx: fireman, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Warpfire Hellspawn", Gfx: fireman, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Wrathfire the Doomed", Gfx: fireman, Trn: "monsters/monsters/wftd.trn", Unique: true},
+	{Name: "Goldblight of the Flame", Gfx: gargoyle, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Nightwing the Cold", Gfx: gargoyle, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Viletouch", Gfx: gargoyle, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Blightfire", Gfx: goatbow, Trn: "monsters/monsters/blf.trn", Unique: true},
+	{Name: "Bloodskin Darkbow", Gfx: goatbow, Trn: "monsters/monsters/bsdb.trn", Unique: true},
+	{Name: "Gorestone", Gfx: goatbow, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Blightstone the Weak", Gfx: goatlord, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Blighthorn Steelmace", Gfx: goatmace, Trn: "monsters/monsters/bhsm.trn", Unique: true},
+	{Name: "Bloodgutter", Gfx: goatmace, Trn: "monsters/monsters/bgbl.trn", Unique: true},
+	{Name: "Deathshade Fleshmaul", Gfx: goatmace, Trn: "monsters/monsters/dsfm.trn", Unique: true},
+	{Name: "Gharbad the Weak", Gfx: goatmace, Trn: "monsters/monsters/bsdb.trn", Unique: true},
+	{Name: "Arch-Bishop Lazarus", Gfx: mage, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Blacktongue", Gfx: mage, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Dreadjudge", Gfx: mage, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "The Vizier", Gfx: mage, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Zhar the Mad", Gfx: mage, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bronzefist Firestone", Gfx: magma, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Firewound the Grim", Gfx: magma, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Blackskull", Gfx: mega, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Gorefeast", Gfx: mega, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Windspawn", Gfx: mega, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Blackstorm", Gfx: rhino, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bluehorn", Gfx: rhino, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Breakspine", Gfx: rhino, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Grimspike", Gfx: rhino, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "El Chupacabras", Gfx: scav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Pulsecrawler", Gfx: scav, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Shadowbite", Gfx: scav, Trn: "monsters/monsters/shbt.trn", Unique: true},
+	{Name: "Spineeater", Gfx: scav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bonehead Keenaxe", Gfx: skelaxe, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Boneripper", Gfx: skelaxe, Trn: "monsters/monsters/br.trn", Unique: true},
+	{Name: "Madeye the Dead", Gfx: skelaxe, Trn: "monsters/monsters/mtd.trn", Unique: true},
+	{Name: "Blackash the Burning", Gfx: skelbow, Trn: "monsters/monsters/bashtb.trn", Unique: true},
+	{Name: "Deadeye", Gfx: skelbow, Trn: "monsters/monsters/de.trn", Unique: true},
+	{Name: "Skullfire", Gfx: skelbow, Trn: "monsters/monsters/skfr.trn", Unique: true},
+	{Name: "Brokenhead Bangshield", Gfx: skelsd, Trn: "monsters/monsters/bhbs.trn", Unique: true},
+	{Name: "Shadowdrinker", Gfx: skelsd, Trn: "monsters/monsters/shdr.trn", Unique: true},
+	{Name: "Skeleton King", Gfx: sking, Trn: "monsters/monsters/genrl.trn", Unique: true},
+	{Name: "Fangskin", Gfx: snake, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Fangspeir", Gfx: snake, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Lord of the Pit", Gfx: snake, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Viperflame", Gfx: snake, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Hazeshifter", Gfx: sneak, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Warpskull", Gfx: sneak, Trn: "monsters/monsters/tspo.trn", Unique: true},
+	{Name: "Blackjade", Gfx: succ, Trn: "monsters/monsters/blkjd.trn", Unique: true},
+	{Name: "Bloodlust", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Fleshdancer", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Red Vex", Gfx: succ, Trn: "monsters/monsters/redv.trn", Unique: true},
+	{Name: "Stareye the Witch", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Webwidow", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Witchfire the Unholy", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Witchmoon", Gfx: succ, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bonesaw the Litch", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Brokenstorm", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Devilskull Sharpbone", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Doomcloud", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Doomgrin the Rotting", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Doomlock", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Festerskull", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Glasskull the Jagged", Gfx: thin, Trn: "monsters/monsters/bhka.trn", Unique: true},
+	{Name: "Stormbane", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "The Flayer", Gfx: thin, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Bloodmoon Soulfire", Gfx: unrav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Howlingire the Shade", Gfx: unrav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Soulslash", Gfx: unrav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Zamphir", Gfx: unrav, Trn: "monsters/monsters/general.trn", Unique: true},
+	{Name: "Goretongue", Gfx: zombie, Trn: "monsters/monsters/pmr.trn", Unique: true},
+	{Name: "Rotcarnage", Gfx: zombie, Trn: "monsters/monsters/rcrn.trn", Unique: true},
+	{Name: "Rotfeast the Hungry", Gfx: zombie, Trn: "monsters/monsters/eth.trn", Unique: true},
+	{Name: "Soulpus", Gfx: zombie, Trn: "monsters/monsters/general.trn", Unique: true},
+}
+
+// otherTrns lists colour transition paths of monster graphics which are not
+// referenced by any CEL config.
+var otherTrns = []string{
+	"monsters/monsters/balr.trn",
+	"monsters/monsters/blodgol.trn",
+	"monsters/monsters/cnselbk.trn",
+	"monsters/monsters/cnselg.trn",
+	"monsters/monsters/cnselgd.trn",
+	"monsters/monsters/default.trn",
+	"monsters/monsters/demsklb.trn",
+	"monsters/monsters/demsklw.trn",
+	"monsters/monsters/demskly.trn",
+	"monsters/monsters/fmanb.trn",
+	"monsters/monsters/fmanr.trn",
+	"monsters/monsters/fmany.trn",
+	"monsters/monsters/genrlll.trn",
+	"monsters/monsters/gsda.trn",
+	"monsters/monsters/guard.trn",
+	"monsters/monsters/nwtc.trn",
+	"monsters/monsters/shcr.trn",
+	"monsters/monsters/succb.trn",
+	"monsters/monsters/succbw.trn",
+	"monsters/monsters/succrw.trn",
+	"monsters/monsters/unravb.trn",
+	"monsters/monsters/unravbk.trn",
+	"monsters/monsters/unravr.trn",
+	"monsters/monsters/vtexl.trn",
+	"monsters/monsters/wrra.trn",
+}
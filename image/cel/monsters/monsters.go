@@ -0,0 +1,223 @@
+// Package monsters provides a catalogue of the monster types and unique
+// monsters of the game, linking each to its graphics and colour transition.
+//
+// The graphics of each monster consist of up to six CL2 files, one per
+// animation, located in a shared graphics directory; e.g. the graphics of
+// zombies are stored in "monsters/zombie/zombie{n,w,a,h,d,s}.cl2". Monster
+// variants and unique monsters reuse the graphics of a base monster, and are
+// told apart by their colour transition (TRN) files.
+package monsters
+
+import (
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+// An Anim specifies a monster animation.
+type Anim byte
+
+// Monster animations.
+const (
+	// Standing.
+	Stand Anim = 'n'
+	// Walking.
+	Walk Anim = 'w'
+	// Attacking.
+	Attack Anim = 'a'
+	// Getting hit.
+	Hit Anim = 'h'
+	// Dying.
+	Death Anim = 'd'
+	// Special action (e.g. ranged attack, or emerging from the ground).
+	Special Anim = 's'
+)
+
+// Anims lists the monster animations.
+var Anims = []Anim{Stand, Walk, Attack, Hit, Death, Special}
+
+// String returns the file name character of the animation.
+func (anim Anim) String() string {
+	return string(anim)
+}
+
+// A Gfx specifies the graphics shared by a group of monsters.
+type Gfx struct {
+	// Graphics directory, relative to "diabdat.mpq"; e.g. "monsters/zombie".
+	Dir string
+	// File name prefix of the animations; e.g. "zombie".
+	Prefix string
+}
+
+// RelPath returns the path to the CL2 file of the given animation, relative to
+// "diabdat.mpq"; e.g. "monsters/zombie/zombiea.cl2".
+func (gfx *Gfx) RelPath(anim Anim) string {
+	return path.Join(gfx.Dir, gfx.Prefix+anim.String()+".cl2")
+}
+
+// Anims returns the animations provided by the graphics, as determined by the
+// presence of a CEL config for each animation.
+func (gfx *Gfx) Anims() []Anim {
+	var anims []Anim
+	for _, anim := range Anims {
+		relPath := gfx.RelPath(anim)
+		if config.RelPaths[path.Base(relPath)] == relPath {
+			anims = append(anims, anim)
+		}
+	}
+	return anims
+}
+
+// A Monster specifies the graphics and colour transition of a monster type or
+// unique monster.
+type Monster struct {
+	// Monster name.
+	Name string
+	// Monster graphics.
+	Gfx *Gfx
+	// Colour transition path, relative to "diabdat.mpq"; or empty if the
+	// monster graphics are used without colour transition.
+	Trn string
+	// Unique monster.
+	Unique bool
+}
+
+// Decode decodes the given animation of the monster from the extracted
+// "diabdat.mpq" directory using colours from the provided palette, and returns
+// the sequential frames of the animation in each of the eight directions. The
+// colour transition of the monster is applied to the palette if present.
+func (m *Monster) Decode(mpqDir string, anim Anim, pal color.Palette) ([][]image.Image, error) {
+	return m.DecodeFS(os.DirFS(mpqDir), anim, pal)
+}
+
+// DecodeFS decodes the given animation of the monster from the game assets of
+// fsys using colours from the provided palette (see Decode).
+func (m *Monster) DecodeFS(fsys fs.FS, anim Anim, pal color.Palette) ([][]image.Image, error) {
+	pal, err := m.palFS(fsys, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dirs, err := cel.DecodeArchiveFS(fsys, m.Gfx.RelPath(anim), pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dirs, nil
+}
+
+// DecodeSprites decodes the given animation of the monster from the extracted
+// "diabdat.mpq" directory using colours from the provided palette, and returns
+// the sprites of the animation in each of the eight directions. The colour
+// transition of the monster is applied to the palette if present.
+func (m *Monster) DecodeSprites(mpqDir string, anim Anim, pal color.Palette) ([]*cel.Sprite, error) {
+	return m.DecodeSpritesFS(os.DirFS(mpqDir), anim, pal)
+}
+
+// DecodeSpritesFS decodes the given animation of the monster from the game
+// assets of fsys using colours from the provided palette (see DecodeSprites).
+func (m *Monster) DecodeSpritesFS(fsys fs.FS, anim Anim, pal color.Palette) ([]*cel.Sprite, error) {
+	pal, err := m.palFS(fsys, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sprites, err := cel.DecodeSpriteArchiveFS(fsys, m.Gfx.RelPath(anim), pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sprites, nil
+}
+
+// palFS returns the given palette with the colour transition of the monster
+// applied, as read from the game assets of fsys; or the palette unchanged if
+// the monster has no colour transition.
+func (m *Monster) palFS(fsys fs.FS, pal color.Palette) (color.Palette, error) {
+	if m.Trn == "" {
+		return pal, nil
+	}
+	trn, err := cel.ParseTrnFS(fsys, m.Trn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return trn.Pal(pal), nil
+}
+
+// Get returns the unique monster or monster type of the given name. Monster
+// names are matched case-insensitively, and unique monsters take precedence
+// over monster types of the same name (e.g. "The Butcher").
+func Get(name string) (*Monster, error) {
+	for _, m := range Uniques {
+		if strings.EqualFold(m.Name, name) {
+			return m, nil
+		}
+	}
+	for _, m := range Types {
+		if strings.EqualFold(m.Name, name) {
+			return m, nil
+		}
+	}
+	return nil, errors.Errorf("unable to locate monster %q", name)
+}
+
+// Render decodes the given animation of the named monster type or unique
+// monster from the extracted "diabdat.mpq" directory, using colours from the
+// provided palette.
+func Render(mpqDir, name string, anim Anim, pal color.Palette) ([][]image.Image, error) {
+	m, err := Get(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return m.Decode(mpqDir, anim, pal)
+}
+
+// Trns returns the sorted colour transition paths of monster graphics known
+// from CEL configs, including those no monster is known to use.
+func Trns() []string {
+	m := make(map[string]bool)
+	for _, relPath := range config.RelPaths {
+		if !strings.HasPrefix(relPath, "monsters/") {
+			continue
+		}
+		conf, err := config.Get(path.Base(relPath))
+		if err != nil {
+			continue
+		}
+		for _, trn := range conf.Trns {
+			m[trn] = true
+		}
+	}
+	for _, trn := range otherTrns {
+		m[trn] = true
+	}
+	var trns []string
+	for trn := range m {
+		trns = append(trns, trn)
+	}
+	sort.Strings(trns)
+	return trns
+}
+
+// UnusedTrns returns the sorted colour transition paths of monster graphics
+// which are not used by any monster type or unique monster.
+func UnusedTrns() []string {
+	used := make(map[string]bool)
+	for _, m := range Types {
+		used[m.Trn] = true
+	}
+	for _, m := range Uniques {
+		used[m.Trn] = true
+	}
+	var unused []string
+	for _, trn := range Trns() {
+		if !used[trn] {
+			unused = append(unused, trn)
+		}
+	}
+	return unused
+}
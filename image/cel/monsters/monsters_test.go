@@ -0,0 +1,50 @@
+package monsters_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/formats/image/cel/monsters"
+)
+
+func TestCatalogue(t *testing.T) {
+	trns := make(map[string]bool)
+	for _, trn := range monsters.Trns() {
+		trns[trn] = true
+	}
+	for _, ms := range [][]*monsters.Monster{monsters.Types, monsters.Uniques} {
+		names := make(map[string]bool)
+		for _, m := range ms {
+			if names[m.Name] {
+				t.Errorf("%q: duplicate monster name", m.Name)
+			}
+			names[m.Name] = true
+			if len(m.Gfx.Anims()) == 0 {
+				t.Errorf("%q: no animations present in %q", m.Name, m.Gfx.Dir)
+			}
+			if m.Trn != "" && !trns[m.Trn] {
+				t.Errorf("%q: unknown colour transition %q", m.Name, m.Trn)
+			}
+		}
+	}
+}
+
+func TestUnusedTrns(t *testing.T) {
+	unused := make(map[string]bool)
+	for _, trn := range monsters.UnusedTrns() {
+		unused[trn] = true
+	}
+	golden := []struct {
+		trn  string
+		want bool
+	}{
+		{trn: "monsters/falspear/salam.trn", want: true},
+		{trn: "monsters/monsters/default.trn", want: true},
+		{trn: "monsters/falspear/blue.trn", want: false},
+		{trn: "monsters/monsters/general.trn", want: false},
+	}
+	for _, g := range golden {
+		if got := unused[g.trn]; got != g.want {
+			t.Errorf("%q: unused mismatch; expected %v, got %v", g.trn, g.want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cel
 
 import (
 	"image/color"
+	"io/fs"
 	"io/ioutil"
 
 	"github.com/pkg/errors"
@@ -25,6 +26,21 @@ func ParsePal(path string) (color.Palette, error) {
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return decodePal(path, buf)
+}
+
+// ParsePalFS parses the named PAL file of fsys and returns the corresponding
+// palette.
+func ParsePalFS(fsys fs.FS, name string) (color.Palette, error) {
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return decodePal(name, buf)
+}
+
+// decodePal decodes the given contents of the named PAL file.
+func decodePal(path string, buf []byte) (color.Palette, error) {
 	const (
 		// Number of colours within a palette.
 		ncolors = 256
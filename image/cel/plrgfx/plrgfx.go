@@ -0,0 +1,385 @@
+// Package plrgfx provides access to the player graphics of the "plrgfx/"
+// directory.
+//
+// The name of each player graphics file encodes the class, armour class,
+// weapon type and action of the animation it contains.
+//
+//    plrgfx/{class}/{c}{a}{w}/{c}{a}{w}{action}.cl2
+//
+// For instance, "plrgfx/warrior/wla/wlaas.cl2" contains the standing animation
+// (as) of a warrior (w) wearing light armour (l) and wielding an axe (a).
+package plrgfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+// A Class specifies the character class of a player.
+type Class uint8
+
+// Character classes.
+const (
+	Warrior Class = iota
+	Rogue
+	Sorcerer
+)
+
+// Classes lists the character classes of players.
+var Classes = []Class{Warrior, Rogue, Sorcerer}
+
+// classNames maps from character class to directory name.
+var classNames = map[Class]string{
+	Warrior:  "warrior",
+	Rogue:    "rogue",
+	Sorcerer: "sorceror", // sic
+}
+
+// classChars maps from character class to file name character.
+var classChars = map[Class]byte{
+	Warrior:  'w',
+	Rogue:    'r',
+	Sorcerer: 's',
+}
+
+// String returns the directory name of the character class.
+func (class Class) String() string {
+	if s, ok := classNames[class]; ok {
+		return s
+	}
+	return fmt.Sprintf("Class(%d)", uint8(class))
+}
+
+// An Armour specifies the armour class of a player.
+type Armour uint8
+
+// Armour classes.
+const (
+	Light Armour = iota
+	Medium
+	Heavy
+)
+
+// Armours lists the armour classes of players.
+var Armours = []Armour{Light, Medium, Heavy}
+
+// armourChars maps from armour class to file name character.
+var armourChars = map[Armour]byte{
+	Light:  'l',
+	Medium: 'm',
+	Heavy:  'h',
+}
+
+// String returns the file name character of the armour class.
+func (armour Armour) String() string {
+	if c, ok := armourChars[armour]; ok {
+		return string(c)
+	}
+	return fmt.Sprintf("Armour(%d)", uint8(armour))
+}
+
+// A Weapon specifies the weapon type wielded by a player.
+type Weapon uint8
+
+// Weapon types.
+const (
+	// No weapon.
+	None Weapon = iota
+	// Shield without weapon.
+	Shield
+	// Sword.
+	Sword
+	// Sword and shield.
+	SwordShield
+	// Bow.
+	Bow
+	// Axe.
+	Axe
+	// Mace or club.
+	Mace
+	// Mace or club and shield.
+	MaceShield
+	// Staff.
+	Staff
+)
+
+// Weapons lists the weapon types of players.
+var Weapons = []Weapon{None, Shield, Sword, SwordShield, Bow, Axe, Mace, MaceShield, Staff}
+
+// weaponChars maps from weapon type to file name character.
+var weaponChars = map[Weapon]byte{
+	None:        'n',
+	Shield:      'u',
+	Sword:       's',
+	SwordShield: 'd',
+	Bow:         'b',
+	Axe:         'a',
+	Mace:        'm',
+	MaceShield:  'h',
+	Staff:       't',
+}
+
+// String returns the file name character of the weapon type.
+func (weapon Weapon) String() string {
+	if c, ok := weaponChars[weapon]; ok {
+		return string(c)
+	}
+	return fmt.Sprintf("Weapon(%d)", uint8(weapon))
+}
+
+// HasShield reports whether the weapon type includes a shield.
+func (weapon Weapon) HasShield() bool {
+	switch weapon {
+	case Shield, SwordShield, MaceShield:
+		return true
+	}
+	return false
+}
+
+// An Action specifies the animated action of a player.
+type Action uint8
+
+// Player actions.
+const (
+	// Standing in the dungeon.
+	Stand Action = iota
+	// Standing in town.
+	StandTown
+	// Walking in the dungeon.
+	Walk
+	// Walking in town.
+	WalkTown
+	// Attacking.
+	Attack
+	// Getting hit.
+	Hit
+	// Blocking with a shield.
+	Block
+	// Dying.
+	Death
+	// Casting a fire spell.
+	CastFire
+	// Casting a lightning spell.
+	CastLightning
+	// Casting other spells.
+	CastMagic
+)
+
+// Actions lists the animated actions of players.
+var Actions = []Action{Stand, StandTown, Walk, WalkTown, Attack, Hit, Block, Death, CastFire, CastLightning, CastMagic}
+
+// actionNames maps from player action to file name suffix.
+var actionNames = map[Action]string{
+	Stand:         "as",
+	StandTown:     "st",
+	Walk:          "aw",
+	WalkTown:      "wl",
+	Attack:        "at",
+	Hit:           "ht",
+	Block:         "bl",
+	Death:         "dt",
+	CastFire:      "fm",
+	CastLightning: "lm",
+	CastMagic:     "qm",
+}
+
+// String returns the file name suffix of the player action.
+func (action Action) String() string {
+	if s, ok := actionNames[action]; ok {
+		return s
+	}
+	return fmt.Sprintf("Action(%d)", uint8(action))
+}
+
+// A Gfx identifies the player graphics of a given class, armour class, weapon
+// type and action.
+type Gfx struct {
+	// Character class.
+	Class Class
+	// Armour class.
+	Armour Armour
+	// Weapon type.
+	Weapon Weapon
+	// Animated action.
+	Action Action
+}
+
+// Valid reports whether the game provides player graphics for the given
+// combination of class, armour class, weapon type and action.
+//
+// The death animation is only provided for players without weapons, as the
+// game always displays dying players unarmed. The block animation is only
+// provided for weapon types which include a shield.
+func (gfx Gfx) Valid() bool {
+	if _, ok := classNames[gfx.Class]; !ok {
+		return false
+	}
+	if _, ok := armourChars[gfx.Armour]; !ok {
+		return false
+	}
+	if _, ok := weaponChars[gfx.Weapon]; !ok {
+		return false
+	}
+	switch gfx.Action {
+	case Death:
+		return gfx.Weapon == None
+	case Block:
+		return gfx.Weapon.HasShield()
+	}
+	_, ok := actionNames[gfx.Action]
+	return ok
+}
+
+// RelPath returns the path to the CL2 file of the player graphics, relative to
+// "diabdat.mpq"; e.g. "plrgfx/warrior/wla/wlaas.cl2".
+func (gfx Gfx) RelPath() string {
+	prefix := gfx.prefix()
+	name := prefix + gfx.Action.String() + ".cl2"
+	return path.Join("plrgfx", gfx.Class.String(), prefix, name)
+}
+
+// prefix returns the class, armour and weapon characters of the player
+// graphics; e.g. "wla".
+func (gfx Gfx) prefix() string {
+	return string([]byte{classChars[gfx.Class], armourChars[gfx.Armour], weaponChars[gfx.Weapon]})
+}
+
+// Parse parses the given path to a player graphics file, relative to
+// "diabdat.mpq"; e.g. "plrgfx/warrior/wla/wlaas.cl2".
+func Parse(relPath string) (Gfx, error) {
+	var gfx Gfx
+	parts := strings.Split(relPath, "/")
+	if len(parts) != 4 || parts[0] != "plrgfx" {
+		return gfx, errors.Errorf("invalid player graphics path %q; expected plrgfx/{class}/{prefix}/{name}.cl2", relPath)
+	}
+	name := parts[3]
+	if len(name) != len("wlaas.cl2") || path.Ext(name) != ".cl2" {
+		return gfx, errors.Errorf("invalid player graphics file name %q in %q", name, relPath)
+	}
+	if len(parts[2]) != 3 || !strings.HasPrefix(name, parts[2]) {
+		return gfx, errors.Errorf("mismatch between directory %q and file name %q in %q", parts[2], name, relPath)
+	}
+	var ok bool
+	if gfx.Class, ok = lookupClass(parts[1], name[0]); !ok {
+		return gfx, errors.Errorf("invalid character class %q in %q", parts[1], relPath)
+	}
+	if gfx.Armour, ok = lookupArmour(name[1]); !ok {
+		return gfx, errors.Errorf("invalid armour class %q in %q", name[1], relPath)
+	}
+	if gfx.Weapon, ok = lookupWeapon(name[2]); !ok {
+		return gfx, errors.Errorf("invalid weapon type %q in %q", name[2], relPath)
+	}
+	if gfx.Action, ok = lookupAction(name[3:5]); !ok {
+		return gfx, errors.Errorf("invalid player action %q in %q", name[3:5], relPath)
+	}
+	if !gfx.Valid() {
+		return gfx, errors.Errorf("invalid combination of weapon type %v and player action %v in %q", gfx.Weapon, gfx.Action, relPath)
+	}
+	if got := gfx.RelPath(); got != relPath {
+		return gfx, errors.Errorf("mismatch between player graphics path %q and canonical path %q", relPath, got)
+	}
+	return gfx, nil
+}
+
+// All returns every valid combination of class, armour class, weapon type and
+// action.
+func All() []Gfx {
+	var gfxs []Gfx
+	for _, class := range Classes {
+		for _, armour := range Armours {
+			for _, weapon := range Weapons {
+				for _, action := range Actions {
+					gfx := Gfx{Class: class, Armour: armour, Weapon: weapon, Action: action}
+					if gfx.Valid() {
+						gfxs = append(gfxs, gfx)
+					}
+				}
+			}
+		}
+	}
+	return gfxs
+}
+
+// Missing returns the valid combinations of class, armour class, weapon type
+// and action which lack a CEL config.
+func Missing() []Gfx {
+	var missing []Gfx
+	for _, gfx := range All() {
+		relPath := gfx.RelPath()
+		if config.RelPaths[path.Base(relPath)] != relPath {
+			missing = append(missing, gfx)
+		}
+	}
+	return missing
+}
+
+// Decode decodes the player graphics from the given extracted "diabdat.mpq"
+// directory using colours from the provided palette, and returns the
+// sequential frames of the animation in each of the eight directions.
+func Decode(mpqDir string, gfx Gfx, pal color.Palette) ([][]image.Image, error) {
+	return DecodeFS(os.DirFS(mpqDir), gfx, pal)
+}
+
+// DecodeFS decodes the player graphics from the game assets of fsys using
+// colours from the provided palette, and returns the sequential frames of the
+// animation in each of the eight directions.
+func DecodeFS(fsys fs.FS, gfx Gfx, pal color.Palette) ([][]image.Image, error) {
+	if !gfx.Valid() {
+		return nil, errors.Errorf("invalid player graphics %v", gfx)
+	}
+	dirs, err := cel.DecodeArchiveFS(fsys, gfx.RelPath(), pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dirs, nil
+}
+
+// lookupClass returns the character class of the given directory name and file
+// name character.
+func lookupClass(dir string, c byte) (Class, bool) {
+	for class, name := range classNames {
+		if name == dir && classChars[class] == c {
+			return class, true
+		}
+	}
+	return 0, false
+}
+
+// lookupArmour returns the armour class of the given file name character.
+func lookupArmour(c byte) (Armour, bool) {
+	for armour, char := range armourChars {
+		if char == c {
+			return armour, true
+		}
+	}
+	return 0, false
+}
+
+// lookupWeapon returns the weapon type of the given file name character.
+func lookupWeapon(c byte) (Weapon, bool) {
+	for weapon, char := range weaponChars {
+		if char == c {
+			return weapon, true
+		}
+	}
+	return 0, false
+}
+
+// lookupAction returns the player action of the given file name suffix.
+func lookupAction(s string) (Action, bool) {
+	for action, name := range actionNames {
+		if name == s {
+			return action, true
+		}
+	}
+	return 0, false
+}
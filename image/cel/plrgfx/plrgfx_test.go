@@ -0,0 +1,53 @@
+package plrgfx_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/formats/image/cel/config"
+	"github.com/sanctuary/formats/image/cel/plrgfx"
+)
+
+func TestParse(t *testing.T) {
+	var relPaths []string
+	for _, relPath := range config.RelPaths {
+		if strings.HasPrefix(relPath, "plrgfx/") {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+	for _, relPath := range relPaths {
+		gfx, err := plrgfx.Parse(relPath)
+		if err != nil {
+			t.Errorf("%q: unable to parse player graphics path; %v", relPath, err)
+			continue
+		}
+		if got := gfx.RelPath(); got != relPath {
+			t.Errorf("%q: relative path mismatch; expected %q, got %q", relPath, relPath, got)
+		}
+	}
+	if got, want := len(plrgfx.All()), len(relPaths); got != want {
+		t.Errorf("player graphics count mismatch; expected %d, got %d", want, got)
+	}
+	for _, gfx := range plrgfx.Missing() {
+		t.Errorf("%q: missing CEL config", gfx.RelPath())
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	golden := []string{
+		"plrgfx/warrior/wla/wladt.cl2", // death animation with weapon
+		"plrgfx/warrior/wla/wlabl.cl2", // block animation without shield
+		"plrgfx/rogue/wla/wlaas.cl2",   // class mismatch
+		"plrgfx/warrior/wlx/wlxas.cl2", // invalid weapon type
+		"plrgfx/warrior/wla/wlaxx.cl2", // invalid action
+		"plrgfx/warrior/w/wlaas.cl2",   // truncated directory prefix
+		"monsters/zombie/zombiea.cl2",  // not player graphics
+	}
+	for _, relPath := range golden {
+		if _, err := plrgfx.Parse(relPath); err == nil {
+			t.Errorf("%q: expected error, got nil", relPath)
+		}
+	}
+}
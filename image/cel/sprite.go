@@ -0,0 +1,134 @@
+package cel
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+// A Sprite is a sequence of frames placed on the tiles of a scene, such as the
+// animation of a monster in one direction.
+type Sprite struct {
+	// Sequential frames of the sprite.
+	Frames []image.Image
+	// Anchor offset of each frame; specifying the position of the bottom-left
+	// corner of the frame relative to the bottom-left corner of the floor tile
+	// the sprite is placed on.
+	Anchors []image.Point
+}
+
+// DecodeSprite decodes the given CEL image using colours from the provided
+// palette, and returns the sprite of its frames.
+func DecodeSprite(path string, pal color.Palette) (*Sprite, error) {
+	conf, err := config.Get(filepath.Base(path))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	frames, err := DecodeAll(path, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return newSprite(frames, conf), nil
+}
+
+// DecodeSpriteFS decodes the named CEL image of fsys using colours from the
+// provided palette, and returns the sprite of its frames.
+func DecodeSpriteFS(fsys fs.FS, name string, pal color.Palette) (*Sprite, error) {
+	conf, err := config.Get(path.Base(name))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	frames, err := DecodeAllFS(fsys, name, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return newSprite(frames, conf), nil
+}
+
+// DecodeSpriteArchive decodes the given CEL archive using colours from the
+// provided palette, and returns the sprites of its embedded CEL images (e.g.
+// one per direction).
+func DecodeSpriteArchive(path string, pal color.Palette) ([]*Sprite, error) {
+	conf, err := config.Get(filepath.Base(path))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	archiveFrames, err := DecodeArchive(path, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sprites := make([]*Sprite, len(archiveFrames))
+	for i, frames := range archiveFrames {
+		sprites[i] = newSprite(frames, conf)
+	}
+	return sprites, nil
+}
+
+// DecodeSpriteArchiveFS decodes the named CEL archive of fsys using colours
+// from the provided palette, and returns the sprites of its embedded CEL images
+// (e.g. one per direction).
+func DecodeSpriteArchiveFS(fsys fs.FS, name string, pal color.Palette) ([]*Sprite, error) {
+	conf, err := config.Get(path.Base(name))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	archiveFrames, err := DecodeArchiveFS(fsys, name, pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sprites := make([]*Sprite, len(archiveFrames))
+	for i, frames := range archiveFrames {
+		sprites[i] = newSprite(frames, conf)
+	}
+	return sprites, nil
+}
+
+// tileWidth specifies the width in pixels of a floor tile.
+const tileWidth = 64
+
+// newSprite returns a new sprite of the given frames, with anchor offsets
+// specified by the image config. Each frame is centered horizontally on the
+// floor tile based on its own width, and offset as specified by the image
+// config.
+func newSprite(frames []image.Image, conf *config.Config) *Sprite {
+	anchors := make([]image.Point, len(frames))
+	for frameNum, frame := range frames {
+		x, ok := conf.FrameAnchorX[frameNum]
+		if !ok {
+			// Fallback to default anchor x offset.
+			x = conf.AnchorX
+		}
+		y, ok := conf.FrameAnchorY[frameNum]
+		if !ok {
+			// Fallback to default anchor y offset.
+			y = conf.AnchorY
+		}
+		// Center frame horizontally on the floor tile.
+		x -= (frame.Bounds().Dx() - tileWidth) / 2
+		anchors[frameNum] = image.Pt(x, y)
+	}
+	return &Sprite{Frames: frames, Anchors: anchors}
+}
+
+// Bounds returns the bounds of the given frame when placed on the floor tile
+// with bottom-left corner at pt.
+func (sprite *Sprite) Bounds(frameNum int, pt image.Point) image.Rectangle {
+	frame := sprite.Frames[frameNum]
+	size := frame.Bounds().Size()
+	min := pt.Add(sprite.Anchors[frameNum]).Sub(image.Pt(0, size.Y))
+	return image.Rectangle{Min: min, Max: min.Add(size)}
+}
+
+// Draw draws the given frame onto dst, placed on the floor tile with
+// bottom-left corner at pt.
+func (sprite *Sprite) Draw(dst draw.Image, frameNum int, pt image.Point) {
+	frame := sprite.Frames[frameNum]
+	dr := sprite.Bounds(frameNum, pt)
+	draw.Draw(dst, dr, frame, frame.Bounds().Min, draw.Over)
+}
@@ -0,0 +1,37 @@
+package cel
+
+import (
+	"image"
+	"testing"
+
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+func TestNewSprite(t *testing.T) {
+	// Frame 1 is displaced and has a frame specific anchor offset; the other
+	// frames fallback to the default anchor offset.
+	conf := &config.Config{
+		AnchorX:      -16,
+		AnchorY:      2,
+		FrameAnchorX: map[int]int{1: 8},
+		FrameAnchorY: map[int]int{1: -4},
+	}
+	frames := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 96, 96)),
+		image.NewRGBA(image.Rect(0, 0, 96, 96)),
+		image.NewRGBA(image.Rect(0, 0, 128, 96)),
+	}
+	sprite := newSprite(frames, conf)
+	golden := []image.Point{
+		// (96-64)/2 = 16
+		{X: -16 - 16, Y: 2},
+		{X: 8 - 16, Y: -4},
+		// (128-64)/2 = 32
+		{X: -16 - 32, Y: 2},
+	}
+	for frameNum, want := range golden {
+		if got := sprite.Anchors[frameNum]; got != want {
+			t.Errorf("frame %d: anchor mismatch; expected %v, got %v", frameNum, want, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cel
 
 import (
 	"image/color"
+	"io/fs"
 	"io/ioutil"
 
 	"github.com/pkg/errors"
@@ -20,11 +21,26 @@ func ParseTrn(path string) (*TransitionTable, error) {
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return decodeTrn(buf), nil
+}
+
+// ParseTrnFS parses the named TRN file of fsys and returns the corresponding
+// colour transition table.
+func ParseTrnFS(fsys fs.FS, name string) (*TransitionTable, error) {
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return decodeTrn(buf), nil
+}
+
+// decodeTrn decodes the given contents of a TRN file.
+func decodeTrn(buf []byte) *TransitionTable {
 	trn := &TransitionTable{}
 	for i, b := range buf {
 		trn.Indices[i] = b
 	}
-	return trn, nil
+	return trn
 }
 
 // A TransitionTable represents a colour transition table.
@@ -34,11 +50,18 @@ type TransitionTable struct {
 }
 
 // Pal returns a new palette created by resolving colours from the source
-// palette using indices from the colour transition table.
+// palette using indices from the colour transition table. The colours of the
+// returned palette record their source palette index, so that frames decoded
+// using the palette retain the palette indices after colour transition (see
+// IndexedImage).
 func (trn *TransitionTable) Pal(src color.Palette) color.Palette {
 	dst := make(color.Palette, len(src))
 	for i, t := range trn.Indices {
-		dst[i] = src[t]
+		c := src[t]
+		if _, ok := c.(palColor); !ok {
+			c = palColor{Color: c, index: t}
+		}
+		dst[i] = c
 	}
 	return dst
 }
@@ -0,0 +1,262 @@
+package cel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel/config"
+)
+
+// A Mismatch describes a discrepancy between the image config of a CEL file and
+// its contents.
+type Mismatch struct {
+	// Path of the CEL file.
+	Path string `json:"path"`
+	// Embedded CEL image number within a CEL archive; or -1 if not applicable.
+	Image int `json:"image"`
+	// Frame number; or -1 if not applicable.
+	Frame int `json:"frame"`
+	// Name of the failed check; one of "archive_offset", "frame_count",
+	// "frame_offset", "header", "pixels", "bytes" or "decode".
+	Check string `json:"check"`
+	// Expected and actual value of the check; or 0 if not applicable.
+	Want int `json:"want"`
+	Got  int `json:"got"`
+	// Description of the mismatch.
+	Msg string `json:"msg"`
+}
+
+// String returns a human-readable description of the mismatch.
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: image %d, frame %d: %s mismatch; %s", m.Path, m.Image, m.Frame, m.Check, m.Msg)
+}
+
+// Verify checks the contents of the given CEL file or CEL archive against its
+// image config, and returns every mismatch found. It checks the archive
+// offsets, the frame count and frame offsets, the frame header size, that each
+// frame decodes to exactly W*H pixels, and that the decoder consumes exactly
+// all bytes of each frame.
+func Verify(path string) ([]Mismatch, error) {
+	// Read file contents.
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return verify(path, filepath.Base(path), buf)
+}
+
+// VerifyFS checks the contents of the named CEL file or CEL archive of fsys
+// against its image config, and returns every mismatch found (see Verify).
+func VerifyFS(fsys fs.FS, name string) ([]Mismatch, error) {
+	// Read file contents.
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return VerifyData(name, buf)
+}
+
+// VerifyData checks the given contents of the named CEL file or CEL archive
+// against its image config, and returns every mismatch found (see Verify). The
+// base name of the file is used to locate its image config.
+func VerifyData(name string, buf []byte) ([]Mismatch, error) {
+	return verify(name, path.Base(name), buf)
+}
+
+// verify checks the given contents of the CEL file at path, with the given base
+// name, against its image config, and returns every mismatch found.
+func verify(path, name string, buf []byte) ([]Mismatch, error) {
+	// Locate image config data.
+	conf, err := config.Get(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	v := &verifier{path: path, conf: conf}
+	if conf.Nimgs == 0 {
+		v.verifyCel(buf, -1)
+		return v.mismatches, nil
+	}
+
+	// Verify archive offsets.
+	//
+	//    celOffsets [nimgs]uint32 // Offset to each embedded CEL image.
+	headerSize := 4 * conf.Nimgs
+	if len(buf) < headerSize {
+		v.errorf(-1, -1, "archive_offset", headerSize, len(buf), "archive header exceeds file size")
+		return v.mismatches, nil
+	}
+	celOffsets := make([]int, conf.Nimgs+1)
+	for i := 0; i < conf.Nimgs; i++ {
+		celOffsets[i] = int(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	celOffsets[conf.Nimgs] = len(buf)
+	if celOffsets[0] != headerSize {
+		v.errorf(0, -1, "archive_offset", headerSize, celOffsets[0], "first embedded CEL image does not follow archive header")
+	}
+	valid := true
+	for i := 0; i < conf.Nimgs; i++ {
+		start, end := celOffsets[i], celOffsets[i+1]
+		if start > end || end > len(buf) {
+			v.errorf(i, -1, "archive_offset", end, start, fmt.Sprintf("invalid range [%d:%d] of embedded CEL image in file of size %d", start, end, len(buf)))
+			valid = false
+		}
+	}
+	if !valid {
+		return v.mismatches, nil
+	}
+
+	// Verify embedded CEL images.
+	nframes := -1
+	for i := 0; i < conf.Nimgs; i++ {
+		n := v.verifyCel(buf[celOffsets[i]:celOffsets[i+1]], i)
+		if nframes == -1 {
+			nframes = n
+		} else if n != -1 && n != nframes {
+			v.errorf(i, -1, "frame_count", nframes, n, "frame count differs from first embedded CEL image")
+		}
+	}
+	return v.mismatches, nil
+}
+
+// A verifier checks the contents of a CEL file against its image config.
+type verifier struct {
+	// Path of the CEL file.
+	path string
+	// Image config of the CEL file.
+	conf *config.Config
+	// Mismatches found.
+	mismatches []Mismatch
+}
+
+// errorf records a mismatch of the given check.
+func (v *verifier) errorf(imgNum, frameNum int, check string, want, got int, msg string) {
+	m := Mismatch{
+		Path:  v.path,
+		Image: imgNum,
+		Frame: frameNum,
+		Check: check,
+		Want:  want,
+		Got:   got,
+		Msg:   msg,
+	}
+	v.mismatches = append(v.mismatches, m)
+}
+
+// verifyCel checks the contents of the given CEL image, and returns its frame
+// count; or -1 if the frame offsets are invalid.
+func (v *verifier) verifyCel(cel []byte, imgNum int) int {
+	// Verify CEL header.
+	//
+	//    nframes      uint32            // Number of frames.
+	//    frameOffsets [nframes+1]uint32 // Offset to each frame.
+	if len(cel) < 4 {
+		v.errorf(imgNum, -1, "frame_count", 4, len(cel), "CEL header exceeds image size")
+		return -1
+	}
+	nframes := int(binary.LittleEndian.Uint32(cel))
+	headerSize := 4 + 4*(nframes+1)
+	if headerSize > len(cel) || nframes < 0 {
+		v.errorf(imgNum, -1, "frame_count", len(cel), headerSize, fmt.Sprintf("CEL header of %d frames exceeds image size", nframes))
+		return -1
+	}
+	for frameNum := range v.conf.FrameWidth {
+		if frameNum >= nframes {
+			v.errorf(imgNum, frameNum, "frame_count", frameNum+1, nframes, "frame width specified for non-existent frame")
+		}
+	}
+	for frameNum := range v.conf.FrameHeight {
+		if frameNum >= nframes {
+			v.errorf(imgNum, frameNum, "frame_count", frameNum+1, nframes, "frame height specified for non-existent frame")
+		}
+	}
+	frameOffsets := make([]int, nframes+1)
+	for i := range frameOffsets {
+		frameOffsets[i] = int(binary.LittleEndian.Uint32(cel[4+4*i:]))
+	}
+	if frameOffsets[0] != headerSize {
+		v.errorf(imgNum, 0, "frame_offset", headerSize, frameOffsets[0], "first frame does not follow CEL header")
+	}
+	if frameOffsets[nframes] != len(cel) {
+		v.errorf(imgNum, nframes, "frame_offset", len(cel), frameOffsets[nframes], "end offset of last frame does not match image size")
+	}
+	for frameNum := 0; frameNum < nframes; frameNum++ {
+		start, end := frameOffsets[frameNum], frameOffsets[frameNum+1]
+		if start > end || end > len(cel) {
+			v.errorf(imgNum, frameNum, "frame_offset", end, start, fmt.Sprintf("invalid range [%d:%d] of frame in image of size %d", start, end, len(cel)))
+			return -1
+		}
+	}
+
+	// Verify frames.
+	for frameNum := 0; frameNum < nframes; frameNum++ {
+		frame := cel[frameOffsets[frameNum]:frameOffsets[frameNum+1]]
+		v.verifyFrame(frame, imgNum, frameNum)
+	}
+	return nframes
+}
+
+// verifyFrame checks the header and pixel data of the given frame.
+func (v *verifier) verifyFrame(frame []byte, imgNum, frameNum int) {
+	// Verify frame header.
+	header := v.conf.Header
+	if header > 0 {
+		if len(frame) < header {
+			v.errorf(imgNum, frameNum, "header", header, len(frame), "frame header exceeds frame size")
+			return
+		}
+		// The first field of the frame header specifies its size.
+		if got := int(binary.LittleEndian.Uint16(frame)); got != header {
+			v.errorf(imgNum, frameNum, "header", header, got, "frame header size mismatch")
+		}
+	}
+
+	// Decode frame.
+	w, ok := v.conf.FrameWidth[frameNum]
+	if !ok {
+		w = v.conf.W
+	}
+	h, ok := v.conf.FrameHeight[frameNum]
+	if !ok {
+		h = v.conf.H
+	}
+	data := frame[header:]
+	npixels, nbytes, err := decodeFrame(getDecoder(v.conf, frameNum), data, w, h)
+	if err != nil {
+		v.errorf(imgNum, frameNum, "decode", 0, 0, err.Error())
+		return
+	}
+	if npixels != w*h {
+		v.errorf(imgNum, frameNum, "pixels", w*h, npixels, fmt.Sprintf("decoded pixel count does not match frame dimensions %dx%d", w, h))
+	}
+	if nbytes != len(data) {
+		v.errorf(imgNum, frameNum, "bytes", len(data), nbytes, "decoder did not consume all pixel data")
+	}
+}
+
+// decodeFrame decodes the given frame pixel data using the provided decoder,
+// and returns the number of pixels drawn and bytes consumed. Decoder panics are
+// recovered and returned as errors.
+func decodeFrame(decode func(d *pixelDrawer, data []byte, pal color.Palette) int, data []byte, w, h int) (npixels, nbytes int, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = errors.Errorf("%v", e)
+		}
+	}()
+	// Use a greyscale palette, as only the pixel count is of interest.
+	pal := make(color.Palette, 256)
+	for i := range pal {
+		pal[i] = color.Gray{Y: uint8(i)}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	d := newPixelDrawer(img, w, h)
+	nbytes = decode(d, data, pal)
+	return d.n, nbytes, nil
+}
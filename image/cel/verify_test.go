@@ -0,0 +1,81 @@
+package cel_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sanctuary/formats/image/cel"
+)
+
+func TestVerify(t *testing.T) {
+	// "ctrlpan/p8bulbs.cel" consists of 88x88 frames of type 1, without frame
+	// header.
+	const npixels = 88 * 88
+	golden := []struct {
+		// Encoded pixel data of a single frame.
+		data []byte
+		// Expected mismatch checks.
+		wants []string
+	}{
+		// Valid frame.
+		{data: transparentRuns(npixels), wants: nil},
+		// Frame with one pixel too few.
+		{data: transparentRuns(npixels - 1), wants: []string{"pixels"}},
+		// Frame with one pixel too many.
+		{data: transparentRuns(npixels + 1), wants: []string{"pixels"}},
+		// Frame with trailing data after the last pixel.
+		{data: append(transparentRuns(npixels), 0x01, 0x00), wants: []string{"bytes"}},
+	}
+	dir, err := ioutil.TempDir("", "cel_verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	celPath := filepath.Join(dir, "p8bulbs.cel")
+	for i, g := range golden {
+		buf := &bytes.Buffer{}
+		header := []uint32{1, 12, uint32(12 + len(g.data))}
+		if err := binary.Write(buf, binary.LittleEndian, header); err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(g.data)
+		if err := ioutil.WriteFile(celPath, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mismatches, err := cel.Verify(celPath)
+		if err != nil {
+			t.Errorf("i=%d: unable to verify CEL image; %v", i, err)
+			continue
+		}
+		var got []string
+		for _, m := range mismatches {
+			got = append(got, m.Check)
+		}
+		if len(got) != len(g.wants) {
+			t.Errorf("i=%d: mismatch checks differ; expected %q, got %q", i, g.wants, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != g.wants[j] {
+				t.Errorf("i=%d: mismatch check differs; expected %q, got %q", i, g.wants[j], got[j])
+			}
+		}
+	}
+}
+
+// transparentRuns returns type 1 pixel data encoding n transparent pixels.
+func transparentRuns(n int) []byte {
+	var data []byte
+	for ; n > 0; n -= 128 {
+		m := n
+		if m > 128 {
+			m = 128
+		}
+		data = append(data, byte(-int8(m-1)-1))
+	}
+	return data
+}
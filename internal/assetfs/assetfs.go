@@ -0,0 +1,25 @@
+// Package assetfs provides the command line tools with access to the game
+// assets of "diabdat.mpq".
+package assetfs
+
+import (
+	"io/fs"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/archive/mpq"
+)
+
+// Open returns the file system of the game assets; read from the given MPQ
+// archive if mpqPath is set, and from the given extracted "diabdat.mpq"
+// directory otherwise.
+func Open(mpqPath, mpqDir string) (fs.FS, error) {
+	if mpqPath != "" {
+		a, err := mpq.Open(mpqPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return a, nil
+	}
+	return os.DirFS(mpqDir), nil
+}
@@ -0,0 +1,49 @@
+// Package diabdat provides tests with access to the game assets of
+// "diabdat.mpq".
+//
+// The assets are read from the MPQ archive specified by the DIABDAT_MPQ
+// environment variable, e.g.
+//
+//    DIABDAT_MPQ=/path/to/diabdat.mpq go test ./...
+//
+// and otherwise from the "diabdat" directory of an extracted "diabdat.mpq",
+// relative to the package directory of the test. An environment variable is
+// used rather than a test flag, as test flags are only defined in the test
+// binaries of packages importing diabdat, and are rejected by the others.
+package diabdat
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/mewkiz/pkg/osutil"
+	"github.com/sanctuary/formats/archive/mpq"
+)
+
+// envMPQ specifies the environment variable holding the path to
+// "diabdat.mpq".
+const envMPQ = "DIABDAT_MPQ"
+
+// mpqDir specifies the path to an extracted "diabdat.mpq".
+const mpqDir = "diabdat"
+
+// FS returns the file system of the game assets. The test is skipped if
+// neither the MPQ archive specified by the DIABDAT_MPQ environment variable nor
+// an extracted "diabdat.mpq" is present.
+func FS(t testing.TB) fs.FS {
+	t.Helper()
+	if mpqPath := os.Getenv(envMPQ); mpqPath != "" {
+		a, err := mpq.Open(mpqPath)
+		if err != nil {
+			t.Fatalf("unable to open MPQ archive %q; %+v", mpqPath, err)
+		}
+		t.Cleanup(func() { a.Close() })
+		return a
+	}
+	// Skip test if extracted "diabdat.mpq" is not present.
+	if !osutil.Exists(mpqDir) {
+		t.Skipf("%q directory not present; use %s to specify the path to %q", mpqDir, envMPQ, "diabdat.mpq")
+	}
+	return os.DirFS(mpqDir)
+}
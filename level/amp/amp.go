@@ -0,0 +1,176 @@
+// Package amp provides access to AMP files.
+//
+// The AMP file of each level (e.g. "levels/l1data/l1.amp") specifies the
+// automap shape of each tile, as defined by the TIL file of the level (e.g.
+// "levels/l1data/l1.til"); such as walls, doors, arches and stairs.
+//
+// Below follows a pseudo-code description of the AMP file format.
+//
+//    // An AMP file consists of a sequence of automap shapes, one per tile of
+//    // the corresponding TIL file.
+//    type AMP []Shape
+//
+//    // A Shape specifies the automap shape of a tile.
+//    type Shape struct {
+//       // Automap shape type (e.g. vertical wall).
+//       //
+//       //    0x00: none
+//       //    0x01: pillar
+//       //    0x02: vertical wall
+//       //    0x03: horizontal wall
+//       //    0x04: vertical and horizontal wall
+//       //    0x05: vertical wall
+//       //    0x06: horizontal wall
+//       //    0x08: vertical wall and horizontal cave wall
+//       //    0x09: horizontal wall and vertical cave wall
+//       //    0x0A: horizontal cave wall
+//       //    0x0B: vertical cave wall
+//       //    0x0C: horizontal and vertical cave wall
+//       Type uint8
+//       // Automap shape flags.
+//       //
+//       //    vertDoor  := flags&0x01 != 0
+//       //    horzDoor  := flags&0x02 != 0
+//       //    vertArch  := flags&0x04 != 0
+//       //    horzArch  := flags&0x08 != 0
+//       //    vertGrate := flags&0x10 != 0
+//       //    horzGrate := flags&0x20 != 0
+//       //    dirt      := flags&0x40 != 0
+//       //    stairs    := flags&0x80 != 0
+//       Flags uint8
+//    }
+package amp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// A Type specifies the automap shape type of a tile.
+type Type uint8
+
+// Automap shape types.
+const (
+	// No automap shape.
+	None Type = 0x00
+	// Stand-alone pillar or other impassable object.
+	Pillar Type = 0x01
+	// Vertical wall, along the top-left edge of the tile.
+	VertWall Type = 0x02
+	// Horizontal wall, along the top-right edge of the tile.
+	HorzWall Type = 0x03
+	// Vertical and horizontal wall.
+	Corner Type = 0x04
+	// Vertical wall (alternative).
+	VertWallAlt Type = 0x05
+	// Horizontal wall (alternative).
+	HorzWallAlt Type = 0x06
+	// Vertical wall and horizontal cave wall.
+	VertWallCaveHorz Type = 0x08
+	// Horizontal wall and vertical cave wall.
+	HorzWallCaveVert Type = 0x09
+	// Horizontal cave wall, along the bottom-left edge of the tile.
+	CaveHorz Type = 0x0A
+	// Vertical cave wall, along the bottom-right edge of the tile.
+	CaveVert Type = 0x0B
+	// Horizontal and vertical cave wall.
+	CaveCorner Type = 0x0C
+)
+
+// Flags is a bitfield of automap shape flags.
+type Flags uint8
+
+// Automap shape flags.
+const (
+	// Door in vertical wall.
+	VertDoor Flags = 0x01
+	// Door in horizontal wall.
+	HorzDoor Flags = 0x02
+	// Arch in vertical wall.
+	VertArch Flags = 0x04
+	// Arch in horizontal wall.
+	HorzArch Flags = 0x08
+	// Grate in vertical wall.
+	VertGrate Flags = 0x10
+	// Grate in horizontal wall.
+	HorzGrate Flags = 0x20
+	// Dirt (e.g. the sides of lava in caves).
+	Dirt Flags = 0x40
+	// Stairs.
+	Stairs Flags = 0x80
+)
+
+// A Shape specifies the automap shape of a tile. The shape at index i of an
+// AMP file corresponds to the tile at index i of the TIL file of the same
+// level.
+type Shape struct {
+	// Automap shape type.
+	Type Type
+	// Automap shape flags.
+	Flags Flags
+}
+
+// Parse parses the given AMP file and returns its automap shapes.
+func Parse(path string) ([]Shape, error) {
+	// Open file for reading.
+	fr, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fr.Close()
+	return Decode(fr)
+}
+
+// ParseFS parses the named AMP file of fsys and returns its automap shapes.
+func ParseFS(fsys fs.FS, name string) ([]Shape, error) {
+	// Open file for reading.
+	fr, err := fsys.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fr.Close()
+	return Decode(fr)
+}
+
+// Decode decodes the AMP file read from r, and returns its automap shapes.
+func Decode(r io.Reader) ([]Shape, error) {
+	br := bufio.NewReader(r)
+
+	// Decode automap shapes.
+	var shapes []Shape
+	for {
+		var x uint16
+		if err := binary.Read(br, binary.LittleEndian, &x); err != nil {
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			return nil, errors.WithStack(err)
+		}
+		shape := Shape{
+			Type:  Type(x & 0xFF),
+			Flags: Flags(x >> 8),
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes, nil
+}
+
+// Encode encodes the given automap shapes in AMP file format, writing to w.
+func Encode(w io.Writer, shapes []Shape) error {
+	bw := bufio.NewWriter(w)
+	for _, shape := range shapes {
+		x := uint16(shape.Flags)<<8 | uint16(shape.Type)
+		if err := binary.Write(bw, binary.LittleEndian, x); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package amp_test
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+
+	"github.com/sanctuary/formats/internal/diabdat"
+	"github.com/sanctuary/formats/level/amp"
+	"github.com/sanctuary/formats/level/til"
+)
+
+func TestEncode(t *testing.T) {
+	// Skip test if "diabdat.mpq" is not present.
+	fsys := diabdat.FS(t)
+
+	golden := []string{
+		"levels/l1data/l1",
+		"levels/l2data/l2",
+		"levels/l3data/l3",
+		"levels/l4data/l4",
+	}
+	for _, relPath := range golden {
+		relAmpPath := relPath + ".amp"
+		want, err := fs.ReadFile(fsys, relAmpPath)
+		if err != nil {
+			t.Errorf("%q: unable to read AMP file; %v", relAmpPath, err)
+			continue
+		}
+		shapes, err := amp.ParseFS(fsys, relAmpPath)
+		if err != nil {
+			t.Errorf("%q: unable to parse AMP file; %v", relAmpPath, err)
+			continue
+		}
+		tiles, err := til.ParseFS(fsys, relPath+".til")
+		if err != nil {
+			t.Errorf("%q: unable to parse TIL file; %v", relAmpPath, err)
+			continue
+		}
+		if len(shapes) < len(tiles) {
+			t.Errorf("%q: automap shape count mismatch; expected >= %d, got %d", relAmpPath, len(tiles), len(shapes))
+		}
+		buf := &bytes.Buffer{}
+		if err := amp.Encode(buf, shapes); err != nil {
+			t.Errorf("%q: unable to encode AMP file; %v", relAmpPath, err)
+			continue
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("%q: AMP file contents mismatch after parse and encode", relAmpPath)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	buf := []byte{0x02, 0x01, 0x04, 0x00, 0x0C, 0xC0}
+	want := []amp.Shape{
+		{Type: amp.VertWall, Flags: amp.VertDoor},
+		{Type: amp.Corner},
+		{Type: amp.CaveCorner, Flags: amp.Dirt | amp.Stairs},
+	}
+	got, err := amp.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("unable to decode AMP file; %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("automap shape count mismatch; expected %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("automap shape %d mismatch; expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	shapes := []amp.Shape{
+		{Type: amp.Corner},
+		{Type: amp.VertWall, Flags: amp.VertDoor},
+	}
+	grid := [][]uint16{
+		{1, 0},
+		{2, 0},
+	}
+	lines, err := amp.Lines(shapes, grid)
+	if err != nil {
+		t.Fatalf("unable to generate automap lines; %v", err)
+	}
+	// Corner (2 walls), and vertical wall with door (2 wall segments and 4 door
+	// segments).
+	if got, want := len(lines), 8; got != want {
+		t.Errorf("line count mismatch; expected %d, got %d", want, got)
+	}
+	img, err := amp.Render(shapes, grid)
+	if err != nil {
+		t.Fatalf("unable to render automap; %v", err)
+	}
+	// Top corner of tile (0, 0).
+	if _, _, _, a := img.At(64, 0).RGBA(); a == 0 {
+		t.Errorf("expected wall pixel at (64, 0)")
+	}
+	if _, err := amp.Render(shapes, [][]uint16{{3}}); err == nil {
+		t.Errorf("expected error for invalid tile ID, got nil")
+	}
+}
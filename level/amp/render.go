@@ -0,0 +1,273 @@
+package amp
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Dimensions of a tile on the automap.
+const (
+	tileWidth  = 64
+	tileHeight = 32
+)
+
+// Automap colours, in the style of the game's automap overlay.
+var (
+	// Colour of walls, stairs and dirt.
+	dimColor = color.RGBA{R: 0x9C, G: 0x7C, B: 0x2C, A: 0xFF}
+	// Colour of doors and grates.
+	brightColor = color.RGBA{R: 0xF0, G: 0xD0, B: 0x68, A: 0xFF}
+)
+
+// A Line is a line segment of the automap, from P0 to P1; a line segment with
+// P0 == P1 is a single point.
+type Line struct {
+	// Start and end point of the line segment.
+	P0, P1 image.Point
+	// Bright line segments are used for doors and grates.
+	Bright bool
+}
+
+// Bounds returns the bounds of the automap of the given tile grid.
+func Bounds(grid [][]uint16) image.Rectangle {
+	w, h := gridSize(grid)
+	return image.Rect(0, 0, (w+h)*tileWidth/2+1, (w+h)*tileHeight/2+1)
+}
+
+// Lines returns the line segments of the automap of the given tile grid, where
+// grid[y][x] specifies the tile ID at the tile coordinate (x, y). Tile IDs are
+// 1-based indices into shapes, and 0 specifies the absence of a tile.
+//
+// The tile coordinate x increases towards the bottom-right and y towards the
+// bottom-left, as illustrated below.
+//
+//           (0,0)
+//        (0,1) (1,0)
+//     (0,2) (1,1) (2,0)
+func Lines(shapes []Shape, grid [][]uint16) ([]Line, error) {
+	_, h := gridSize(grid)
+	var lines []Line
+	for y, row := range grid {
+		for x, tileID := range row {
+			if tileID == 0 {
+				continue
+			}
+			if int(tileID) > len(shapes) {
+				return nil, errors.Errorf("invalid tile ID %d at (%d, %d); expected 1 <= id <= %d", tileID, x, y, len(shapes))
+			}
+			// Center of the tile.
+			c := image.Pt((x-y+h)*tileWidth/2, (x+y+1)*tileHeight/2)
+			lines = appendShape(lines, shapes[tileID-1], c)
+		}
+	}
+	return lines, nil
+}
+
+// appendShape appends the line segments of the given automap shape, centered at
+// c, to lines.
+func appendShape(lines []Line, shape Shape, c image.Point) []Line {
+	// Corners of the tile.
+	top := c.Add(image.Pt(0, -tileHeight/2))
+	right := c.Add(image.Pt(tileWidth/2, 0))
+	bottom := c.Add(image.Pt(0, tileHeight/2))
+	left := c.Add(image.Pt(-tileWidth/2, 0))
+
+	var vert, horz, caveVert, caveHorz bool
+	switch shape.Type {
+	case Pillar:
+		// Small diamond in the upper half of the tile.
+		l := c.Add(image.Pt(-tileWidth/4, -tileHeight/4))
+		r := c.Add(image.Pt(tileWidth/4, -tileHeight/4))
+		lines = append(lines,
+			Line{P0: top, P1: l},
+			Line{P0: top, P1: r},
+			Line{P0: c, P1: l},
+			Line{P0: c, P1: r},
+		)
+	case VertWall, VertWallAlt:
+		vert = true
+	case HorzWall, HorzWallAlt:
+		horz = true
+	case Corner:
+		vert, horz = true, true
+	case VertWallCaveHorz:
+		vert, caveHorz = true, true
+	case HorzWallCaveVert:
+		horz, caveVert = true, true
+	case CaveHorz:
+		caveHorz = true
+	case CaveVert:
+		caveVert = true
+	case CaveCorner:
+		caveHorz, caveVert = true, true
+	}
+	if vert {
+		lines = appendWall(lines, top, left, shape.Flags&VertDoor != 0, shape.Flags&VertArch != 0, shape.Flags&VertGrate != 0)
+	}
+	if horz {
+		lines = appendWall(lines, top, right, shape.Flags&HorzDoor != 0, shape.Flags&HorzArch != 0, shape.Flags&HorzGrate != 0)
+	}
+	if caveHorz {
+		lines = append(lines, Line{P0: left, P1: bottom})
+	}
+	if caveVert {
+		lines = append(lines, Line{P0: right, P1: bottom})
+	}
+	if shape.Flags&Stairs != 0 {
+		// Steps parallel to the top-left edge of the tile.
+		const nsteps = 4
+		for i := 1; i < nsteps; i++ {
+			p0 := lerp(top, right, i, nsteps)
+			p1 := lerp(left, bottom, i, nsteps)
+			lines = append(lines, Line{P0: p0, P1: p1})
+		}
+	}
+	if shape.Flags&Dirt != 0 {
+		// Scattered dots.
+		dots := []image.Point{
+			c,
+			c.Add(image.Pt(-tileWidth/8, -tileHeight/8)),
+			c.Add(image.Pt(tileWidth/8, -tileHeight/8)),
+			c.Add(image.Pt(-tileWidth/8, tileHeight/8)),
+			c.Add(image.Pt(tileWidth/8, tileHeight/8)),
+		}
+		for _, dot := range dots {
+			lines = append(lines, Line{P0: dot, P1: dot})
+		}
+	}
+	return lines
+}
+
+// appendWall appends the line segments of a wall from p0 to p1 to lines. Doors
+// and arches leave an opening in the middle of the wall; doors are marked by a
+// small diamond and grates by a bright segment across the opening.
+func appendWall(lines []Line, p0, p1 image.Point, door, arch, grate bool) []Line {
+	if !door && !arch && !grate {
+		return append(lines, Line{P0: p0, P1: p1})
+	}
+	q1 := lerp(p0, p1, 1, 4)
+	q3 := lerp(p0, p1, 3, 4)
+	lines = append(lines, Line{P0: p0, P1: q1}, Line{P0: q3, P1: p1})
+	switch {
+	case door:
+		m := lerp(p0, p1, 1, 2)
+		l := m.Add(image.Pt(-tileWidth/8, 0))
+		r := m.Add(image.Pt(tileWidth/8, 0))
+		t := m.Add(image.Pt(0, -tileHeight/8))
+		b := m.Add(image.Pt(0, tileHeight/8))
+		lines = append(lines,
+			Line{P0: t, P1: l, Bright: true},
+			Line{P0: t, P1: r, Bright: true},
+			Line{P0: b, P1: l, Bright: true},
+			Line{P0: b, P1: r, Bright: true},
+		)
+	case grate:
+		lines = append(lines, Line{P0: q1, P1: q3, Bright: true})
+	}
+	return lines
+}
+
+// Render renders the automap of the given tile grid as a raster image, with
+// transparent background. See Lines for a description of grid.
+func Render(shapes []Shape, grid [][]uint16) (*image.RGBA, error) {
+	lines, err := Lines(shapes, grid)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	img := image.NewRGBA(Bounds(grid))
+	for _, line := range lines {
+		c := dimColor
+		if line.Bright {
+			c = brightColor
+		}
+		drawLine(img, line.P0, line.P1, c)
+	}
+	return img, nil
+}
+
+// RenderSVG renders the automap of the given tile grid as an SVG vector image,
+// writing to w. See Lines for a description of grid.
+func RenderSVG(w io.Writer, shapes []Shape, grid [][]uint16) error {
+	lines, err := Lines(shapes, grid)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	bounds := Bounds(grid)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\">\n", bounds.Dx(), bounds.Dy())
+	fmt.Fprintf(bw, "<g stroke-width=\"1\" stroke-linecap=\"square\">\n")
+	for _, line := range lines {
+		c := dimColor
+		if line.Bright {
+			c = brightColor
+		}
+		fmt.Fprintf(bw, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#%02X%02X%02X\"/>\n", line.P0.X, line.P0.Y, line.P1.X, line.P1.Y, c.R, c.G, c.B)
+	}
+	fmt.Fprintf(bw, "</g>\n</svg>\n")
+	if err := bw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// drawLine draws a line from p0 to p1 onto dst, using Bresenham's line
+// algorithm.
+func drawLine(dst *image.RGBA, p0, p1 image.Point, c color.Color) {
+	dx, sx := abs(p1.X-p0.X), sign(p1.X-p0.X)
+	dy, sy := -abs(p1.Y-p0.Y), sign(p1.Y-p0.Y)
+	e := dx + dy
+	for x, y := p0.X, p0.Y; ; {
+		dst.Set(x, y, c)
+		if x == p1.X && y == p1.Y {
+			break
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y += sy
+		}
+	}
+}
+
+// lerp returns the point at i/n of the way from p0 to p1.
+func lerp(p0, p1 image.Point, i, n int) image.Point {
+	return p0.Add(p1.Sub(p0).Mul(i).Div(n))
+}
+
+// gridSize returns the width and height of the given tile grid.
+func gridSize(grid [][]uint16) (w, h int) {
+	for _, row := range grid {
+		if len(row) > w {
+			w = len(row)
+		}
+	}
+	return w, len(grid)
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// sign returns the sign of x.
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,250 @@
+// Package atlas packs the dungeon pieces and tiles of a level into a texture
+// atlas.
+//
+// The atlas consists of one or more page images, each at most 2048x2048 pixels,
+// and is accompanied by a JSON index, which records the page and rectangle of
+// each dungeon piece and tile, along with the blocks (for dungeon pieces) or
+// dungeon pieces (for tiles) it is composed of. Identical images are stored
+// once in the atlas, and share a page and rectangle in the index.
+package atlas
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/til"
+)
+
+// Maximum dimensions of atlas page images.
+const (
+	maxWidth  = 2048
+	maxHeight = 2048
+)
+
+// Entry kinds.
+const (
+	// Dungeon piece entry.
+	KindDPiece = "dpiece"
+	// Tile entry.
+	KindTile = "tile"
+)
+
+// An Index records the contents of an atlas.
+type Index struct {
+	// Page images of the atlas.
+	Pages []Page `json:"pages"`
+	// Dungeon piece and tile entries.
+	Entries []Entry `json:"entries"`
+}
+
+// A Page records a page image of an atlas.
+type Page struct {
+	// Path of the page image, relative to the index.
+	Image string `json:"image"`
+	// Dimensions of the page image.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// An Entry records the location of a dungeon piece or tile within an atlas.
+type Entry struct {
+	// Entry kind; either KindDPiece or KindTile.
+	Kind string `json:"kind"`
+	// 1-based dungeon piece ID or tile ID.
+	ID int `json:"id"`
+	// Index into the pages of the atlas.
+	Page int `json:"page"`
+	// Rectangle within the page image.
+	Rect Rect `json:"rect"`
+	// Blocks of the dungeon piece; or nil for tiles.
+	Blocks []Block `json:"blocks,omitempty"`
+	// 1-based dungeon piece IDs of the tile (top, right, left, bottom); or nil
+	// for dungeon pieces.
+	DPieces []int `json:"dpieces,omitempty"`
+}
+
+// A Rect is a rectangle within an atlas page image.
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// A Block records a block of a dungeon piece.
+type Block struct {
+	// Frame number in the level CEL file; or 0 if empty.
+	FrameNum int `json:"frame"`
+	// Frame type, specifying the CEL decoding algorithm of the frame.
+	FrameType int `json:"type"`
+}
+
+// Pack renders the given dungeon pieces and tiles, and packs them into the page
+// images of an atlas. The name specifies the path of the page images recorded
+// in the index, without extension; e.g. "l1" for "l1_0.png", "l1_1.png", etc.
+func Pack(name string, dpieces []min.DPiece, tiles []til.Tile, levelFrames []image.Image) ([]*image.RGBA, *Index, error) {
+	p := newPacker()
+	for i, dpiece := range dpieces {
+		img, err := dpiece.SafeImage(levelFrames)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid dungeon piece %d", i+1)
+		}
+		entry := Entry{Kind: KindDPiece, ID: i + 1}
+		for _, block := range dpiece.Blocks {
+			entry.Blocks = append(entry.Blocks, Block{FrameNum: block.FrameNum, FrameType: block.FrameType})
+		}
+		p.add(entry, img)
+	}
+	for i, tile := range tiles {
+		img, err := tile.SafeImage(dpieces, levelFrames)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid tile %d", i+1)
+		}
+		entry := Entry{
+			Kind:    KindTile,
+			ID:      i + 1,
+			DPieces: []int{int(tile.Top) + 1, int(tile.Right) + 1, int(tile.Left) + 1, int(tile.Bottom) + 1},
+		}
+		p.add(entry, img)
+	}
+	pages, index := p.pack(name)
+	return pages, index, nil
+}
+
+// EncodeIndex encodes the given index in JSON format, writing to w.
+func EncodeIndex(w io.Writer, index *Index) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(index); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DecodeIndex decodes the JSON index read from r.
+func DecodeIndex(r io.Reader) (*Index, error) {
+	index := &Index{}
+	if err := json.NewDecoder(r).Decode(index); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return index, nil
+}
+
+// A packer packs images into an atlas image, storing identical images once.
+type packer struct {
+	// Entries in order of addition.
+	entries []Entry
+	// Index into imgs of the image of each entry.
+	imgIndex []int
+	// Unique images.
+	imgs []*image.RGBA
+	// Index into imgs of each unique image, keyed by checksum.
+	seen map[[sha1.Size]byte]int
+}
+
+// newPacker returns a new packer.
+func newPacker() *packer {
+	return &packer{seen: make(map[[sha1.Size]byte]int)}
+}
+
+// add adds the given entry and its image to the packer.
+func (p *packer) add(entry Entry, src image.Image) {
+	// Normalize image to determine its checksum.
+	bounds := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), src, bounds.Min, draw.Src)
+	h := sha1.New()
+	h.Write([]byte{byte(bounds.Dx() >> 8), byte(bounds.Dx()), byte(bounds.Dy() >> 8), byte(bounds.Dy())})
+	h.Write(img.Pix)
+	var sum [sha1.Size]byte
+	copy(sum[:], h.Sum(nil))
+	i, ok := p.seen[sum]
+	if !ok {
+		i = len(p.imgs)
+		p.imgs = append(p.imgs, img)
+		p.seen[sum] = i
+	}
+	p.entries = append(p.entries, entry)
+	p.imgIndex = append(p.imgIndex, i)
+}
+
+// pack packs the unique images into atlas page images using shelf packing,
+// with images sorted by decreasing height, and returns the page images and
+// index.
+func (p *packer) pack(name string) ([]*image.RGBA, *Index) {
+	order := make([]int, len(p.imgs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return p.imgs[order[i]].Bounds().Dy() > p.imgs[order[j]].Bounds().Dy()
+	})
+
+	// Place images on shelves, starting a new page when the next shelf exceeds
+	// the maximum page height.
+	pageIndex := make([]int, len(p.imgs))
+	rects := make([]Rect, len(p.imgs))
+	var sizes []image.Point
+	page, x, y, shelfHeight := 0, 0, 0, 0
+	for _, i := range order {
+		size := p.imgs[i].Bounds().Size()
+		if x > 0 && x+size.X > maxWidth {
+			// Start new shelf.
+			x = 0
+			y += shelfHeight
+			shelfHeight = 0
+		}
+		if x == 0 && y > 0 && y+size.Y > maxHeight {
+			// Start new page.
+			page++
+			y = 0
+		}
+		if page == len(sizes) {
+			sizes = append(sizes, image.Point{})
+		}
+		pageIndex[i] = page
+		rects[i] = Rect{X: x, Y: y, W: size.X, H: size.Y}
+		x += size.X
+		if size.Y > shelfHeight {
+			shelfHeight = size.Y
+		}
+		if x > sizes[page].X {
+			sizes[page].X = x
+		}
+		if y+shelfHeight > sizes[page].Y {
+			sizes[page].Y = y + shelfHeight
+		}
+	}
+
+	// Draw page images.
+	index := &Index{}
+	pages := make([]*image.RGBA, len(sizes))
+	for page, size := range sizes {
+		pages[page] = image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+		index.Pages = append(index.Pages, Page{
+			Image:  fmt.Sprintf("%s_%d.png", name, page),
+			Width:  size.X,
+			Height: size.Y,
+		})
+	}
+	for i, img := range p.imgs {
+		r := rects[i]
+		dr := image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+		draw.Draw(pages[pageIndex[i]], dr, img, image.ZP, draw.Src)
+	}
+
+	// Record entries.
+	for i, entry := range p.entries {
+		entry.Page = pageIndex[p.imgIndex[i]]
+		entry.Rect = rects[p.imgIndex[i]]
+		index.Entries = append(index.Entries, entry)
+	}
+	return pages, index
+}
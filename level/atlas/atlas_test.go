@@ -0,0 +1,126 @@
+package atlas_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/formats/level/atlas"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/til"
+)
+
+func TestPack(t *testing.T) {
+	frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	levelFrames := []image.Image{frame}
+	empty := min.DPiece{Blocks: make([]min.Block, 10)}
+	floor := min.DPiece{Blocks: make([]min.Block, 10)}
+	floor.Blocks[8].FrameNum = 1
+	dpieces := []min.DPiece{empty, floor, floor}
+	tiles := []til.Tile{
+		{Top: 1, Right: 1, Left: 1, Bottom: 1},
+		{Top: 2, Right: 2, Left: 2, Bottom: 2},
+		{Top: 0, Right: 0, Left: 0, Bottom: 0},
+	}
+	pages, index, err := atlas.Pack("l1", dpieces, tiles, levelFrames)
+	if err != nil {
+		t.Fatalf("unable to pack atlas; %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("page count mismatch; expected 1, got %d", len(pages))
+	}
+	img := pages[0]
+	want := atlas.Page{Image: "l1_0.png", Width: 2*128 + 2*64, Height: 192}
+	if !reflect.DeepEqual(index.Pages, []atlas.Page{want}) {
+		t.Errorf("pages mismatch; expected %v, got %v", []atlas.Page{want}, index.Pages)
+	}
+	if got, want := len(index.Entries), len(dpieces)+len(tiles); got != want {
+		t.Fatalf("entry count mismatch; expected %d, got %d", want, got)
+	}
+	// Identical dungeon pieces and tiles share rectangles.
+	if index.Entries[1].Rect != index.Entries[2].Rect {
+		t.Errorf("expected identical dungeon pieces to share rectangle; got %v and %v", index.Entries[1].Rect, index.Entries[2].Rect)
+	}
+	if index.Entries[3].Rect != index.Entries[4].Rect {
+		t.Errorf("expected identical tiles to share rectangle; got %v and %v", index.Entries[3].Rect, index.Entries[4].Rect)
+	}
+	if index.Entries[0].Rect == index.Entries[1].Rect {
+		t.Errorf("expected distinct dungeon pieces to have distinct rectangles")
+	}
+	// 2 unique dungeon pieces of 64x160 and 2 unique tiles of 128x192.
+	if got, want := img.Bounds(), image.Rect(0, 0, 2*128+2*64, 192); got != want {
+		t.Errorf("atlas bounds mismatch; expected %v, got %v", want, got)
+	}
+	r := index.Entries[1].Rect
+	if _, _, _, a := img.At(r.X, r.Y+r.H-1).RGBA(); a == 0 {
+		t.Errorf("expected floor pixel at bottom-left of dungeon piece 2")
+	}
+	if got, want := index.Entries[3].DPieces, []int{2, 2, 2, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tile dungeon pieces mismatch; expected %v, got %v", want, got)
+	}
+
+	// Invalid dungeon pieces are reported using 1-based IDs.
+	broken := min.DPiece{Blocks: make([]min.Block, 10)}
+	broken.Blocks[8].FrameNum = 2
+	dpieces[2] = broken
+	if _, _, err := atlas.Pack("l1", dpieces, tiles, levelFrames); err == nil || !strings.Contains(err.Error(), "dungeon piece 3") {
+		t.Errorf("expected error for dungeon piece 3, got %v", err)
+	}
+
+	// Round-trip index.
+	buf := &bytes.Buffer{}
+	if err := atlas.EncodeIndex(buf, index); err != nil {
+		t.Fatalf("unable to encode index; %v", err)
+	}
+	got, err := atlas.DecodeIndex(buf)
+	if err != nil {
+		t.Fatalf("unable to decode index; %v", err)
+	}
+	if !reflect.DeepEqual(got, index) {
+		t.Errorf("index mismatch after encode and decode")
+	}
+}
+
+func TestPackPages(t *testing.T) {
+	// 13 shelves of 32 unique dungeon pieces of 64x160 exceed the maximum page
+	// height of 2048 pixels.
+	const n = 13 * 32
+	var levelFrames []image.Image
+	var dpieces []min.DPiece
+	for i := 0; i < n; i++ {
+		frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+		c := color.RGBA{R: uint8(i), G: uint8(i >> 8), A: 0xFF}
+		draw.Draw(frame, frame.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+		levelFrames = append(levelFrames, frame)
+		dpiece := min.DPiece{Blocks: make([]min.Block, 10)}
+		dpiece.Blocks[8].FrameNum = i + 1
+		dpieces = append(dpieces, dpiece)
+	}
+	pages, index, err := atlas.Pack("l1", dpieces, nil, levelFrames)
+	if err != nil {
+		t.Fatalf("unable to pack atlas; %v", err)
+	}
+	if len(pages) != 2 || len(index.Pages) != 2 {
+		t.Fatalf("page count mismatch; expected 2, got %d", len(pages))
+	}
+	for i, page := range pages {
+		if page.Bounds().Dx() > 2048 || page.Bounds().Dy() > 2048 {
+			t.Errorf("page %d exceeds maximum dimensions; got %v", i, page.Bounds())
+		}
+		if got, want := page.Bounds(), image.Rect(0, 0, index.Pages[i].Width, index.Pages[i].Height); got != want {
+			t.Errorf("page %d bounds mismatch; expected %v, got %v", i, want, got)
+		}
+	}
+	if got, want := index.Pages[1].Image, "l1_1.png"; got != want {
+		t.Errorf("page image mismatch; expected %q, got %q", want, got)
+	}
+	last := index.Entries[n-1]
+	if last.Page != 1 || last.Rect.Y != 0 {
+		t.Errorf("expected last dungeon piece at top of second page; got page %d, rect %v", last.Page, last.Rect)
+	}
+}
@@ -0,0 +1,238 @@
+// Package dun implements access to DUN files.
+//
+// A DUN file (e.g. "levels/l1data/sklkng.dun") specifies the arrangement of
+// tiles - as defined by the TIL file of the level - forming a quest level, a
+// town sector or a set piece, along with the placement of items, monsters and
+// objects, and the transparency regions of the dungeon.
+//
+// Below follows a pseudo-code description of the DUN file format.
+//
+//    // A DUN file consists of a tile grid, followed by optional layers of
+//    // twice the resolution.
+//    type DUN struct {
+//       // Width of the tile grid.
+//       Width uint16
+//       // Height of the tile grid.
+//       Height uint16
+//       // Tile IDs (1-based indices into the TIL file; or 0 for none).
+//       Tiles [Height][Width]uint16
+//       // Item IDs.
+//       Items [2*Height][2*Width]uint16
+//       // Monster IDs.
+//       Monsters [2*Height][2*Width]uint16
+//       // Object IDs.
+//       Objects [2*Height][2*Width]uint16
+//       // Transparency region IDs.
+//       Transparency [2*Height][2*Width]uint16
+//    }
+//
+// Each layer following the tile grid is optional, but a layer is only present
+// if every preceding layer is present.
+package dun
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// A Dungeon specifies the contents of a DUN file.
+type Dungeon struct {
+	// Width and height of the tile grid.
+	Width, Height int
+	// Tile IDs, where Tiles[y][x] specifies the tile at the tile coordinate (x,
+	// y). Tile IDs are 1-based indices into the tiles of the TIL file, and 0
+	// specifies the absence of a tile.
+	Tiles [][]uint16
+	// Item IDs, where Items[y][x] specifies the item at the dungeon piece
+	// coordinate (x, y); or nil if not present.
+	Items [][]uint16
+	// Monster IDs, where Monsters[y][x] specifies the monster at the dungeon
+	// piece coordinate (x, y); or nil if not present.
+	Monsters [][]uint16
+	// Object IDs, where Objects[y][x] specifies the object at the dungeon piece
+	// coordinate (x, y); or nil if not present.
+	Objects [][]uint16
+	// Transparency region IDs, where Transparency[y][x] specifies the
+	// transparency region of the dungeon piece coordinate (x, y); or nil if not
+	// present.
+	Transparency [][]uint16
+}
+
+// Parse parses the given DUN file.
+//
+// The "levels/l1data/banner2.dun" file of the game ends in the middle of a
+// layer; for this file, the missing entries of the truncated layer are zero.
+func Parse(path string) (*Dungeon, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lenient := filepath.Base(path) == "banner2.dun"
+	dun, err := decode(buf, lenient)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q", path)
+	}
+	return dun, nil
+}
+
+// ParseFS parses the named DUN file of fsys. The truncated
+// "levels/l1data/banner2.dun" file is handled as by Parse.
+func ParseFS(fsys fs.FS, name string) (*Dungeon, error) {
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lenient := path.Base(name) == "banner2.dun"
+	dun, err := decode(buf, lenient)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %q", name)
+	}
+	return dun, nil
+}
+
+// Decode decodes the DUN file read from r. A truncated layer is reported as an
+// error.
+func Decode(r io.Reader) (*Dungeon, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return decode(buf, false)
+}
+
+// decode decodes the given DUN file contents. If lenient is set, the missing
+// entries of a truncated layer are zero and trailing data is ignored, instead of
+// reported as an error.
+func decode(buf []byte, lenient bool) (*Dungeon, error) {
+	// Decode header.
+	if len(buf) < 4 {
+		return nil, errors.Errorf("truncated header; expected 4 bytes, got %d", len(buf))
+	}
+	dun := &Dungeon{
+		Width:  int(binary.LittleEndian.Uint16(buf[0:])),
+		Height: int(binary.LittleEndian.Uint16(buf[2:])),
+	}
+	buf = buf[4:]
+
+	// Decode tile grid.
+	var err error
+	dun.Tiles, buf, err = decodeLayer(buf, "tile", dun.Width, dun.Height, lenient)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Decode optional layers.
+	layers := []struct {
+		name string
+		dst  *[][]uint16
+	}{
+		{name: "item", dst: &dun.Items},
+		{name: "monster", dst: &dun.Monsters},
+		{name: "object", dst: &dun.Objects},
+		{name: "transparency", dst: &dun.Transparency},
+	}
+	for _, layer := range layers {
+		if len(buf) == 0 {
+			break
+		}
+		*layer.dst, buf, err = decodeLayer(buf, layer.name, 2*dun.Width, 2*dun.Height, lenient)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if len(buf) > 0 && !lenient {
+		return nil, errors.Errorf("trailing data of %d bytes after last layer", len(buf))
+	}
+	return dun, nil
+}
+
+// decodeLayer decodes a layer of the given dimensions from buf, and returns the
+// layer and the remaining bytes of buf.
+func decodeLayer(buf []byte, name string, w, h int, lenient bool) ([][]uint16, []byte, error) {
+	size := 2 * w * h
+	if len(buf) < size {
+		if !lenient {
+			return nil, nil, errors.Errorf("truncated %s layer; expected %d bytes, got %d", name, size, len(buf))
+		}
+		// Pad the truncated layer with zeros.
+		padded := make([]byte, size)
+		copy(padded, buf)
+		buf = padded
+	}
+	layer := make([][]uint16, h)
+	for y := range layer {
+		layer[y] = make([]uint16, w)
+		for x := range layer[y] {
+			layer[y][x] = binary.LittleEndian.Uint16(buf[2*(y*w+x):])
+		}
+	}
+	return layer, buf[size:], nil
+}
+
+// Encode encodes the given dungeon in DUN file format, writing to w. Layers are
+// encoded up to the last present layer; absent layers preceding it are encoded
+// as zeros.
+func Encode(w io.Writer, dun *Dungeon) error {
+	buf := &bytes.Buffer{}
+	if dun.Width < 0 || dun.Height < 0 || dun.Width > 0xFFFF || dun.Height > 0xFFFF {
+		return errors.Errorf("invalid dungeon dimensions %dx%d; expected 0x0 to 65535x65535", dun.Width, dun.Height)
+	}
+	hdr := [2]uint16{uint16(dun.Width), uint16(dun.Height)}
+	if err := binary.Write(buf, binary.LittleEndian, hdr); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := encodeLayer(buf, "tile", dun.Tiles, dun.Width, dun.Height); err != nil {
+		return errors.WithStack(err)
+	}
+	layers := [][][]uint16{dun.Items, dun.Monsters, dun.Objects, dun.Transparency}
+	names := []string{"item", "monster", "object", "transparency"}
+	n := 0
+	for i, layer := range layers {
+		if layer != nil {
+			n = i + 1
+		}
+	}
+	for i := 0; i < n; i++ {
+		if err := encodeLayer(buf, names[i], layers[i], 2*dun.Width, 2*dun.Height); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// encodeLayer encodes the given layer of the specified dimensions, writing to
+// buf. A nil layer is encoded as zeros.
+func encodeLayer(buf *bytes.Buffer, name string, layer [][]uint16, w, h int) error {
+	if layer != nil && len(layer) != h {
+		return errors.Errorf("%s layer height mismatch; expected %d, got %d", name, h, len(layer))
+	}
+	var x [2]byte
+	for i := 0; i < h; i++ {
+		var row []uint16
+		if layer != nil {
+			row = layer[i]
+			if len(row) != w {
+				return errors.Errorf("%s layer width mismatch at row %d; expected %d, got %d", name, i, w, len(row))
+			}
+		}
+		for j := 0; j < w; j++ {
+			var v uint16
+			if row != nil {
+				v = row[j]
+			}
+			binary.LittleEndian.PutUint16(x[:], v)
+			buf.Write(x[:])
+		}
+	}
+	return nil
+}
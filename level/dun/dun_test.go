@@ -0,0 +1,122 @@
+package dun_test
+
+import (
+	"bytes"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sanctuary/formats/assets"
+	"github.com/sanctuary/formats/internal/diabdat"
+	"github.com/sanctuary/formats/level/dun"
+)
+
+func TestEncode(t *testing.T) {
+	// Skip test if "diabdat.mpq" is not present.
+	fsys := diabdat.FS(t)
+
+	for _, relDunPath := range assets.RelPaths(assets.DUN) {
+		want, err := fs.ReadFile(fsys, relDunPath)
+		if err != nil {
+			t.Errorf("%q: unable to read DUN file; %v", relDunPath, err)
+			continue
+		}
+		d, err := dun.ParseFS(fsys, relDunPath)
+		if err != nil {
+			t.Errorf("%q: unable to parse DUN file; %v", relDunPath, err)
+			continue
+		}
+		if filepath.Base(relDunPath) == "banner2.dun" {
+			// Truncated layer is zero-padded on parse.
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := dun.Encode(buf, d); err != nil {
+			t.Errorf("%q: unable to encode DUN file; %v", relDunPath, err)
+			continue
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("%q: DUN file contents mismatch after parse and encode", relDunPath)
+		}
+	}
+}
+
+// newDungeon returns a dungeon of 2x1 tiles with items and monsters, for use
+// as a test fixture.
+func newDungeon() *dun.Dungeon {
+	return &dun.Dungeon{
+		Width:  2,
+		Height: 1,
+		Tiles:  [][]uint16{{1, 2}},
+		Items: [][]uint16{
+			{0, 0, 0, 0},
+			{0, 3, 0, 0},
+		},
+		Monsters: [][]uint16{
+			{0, 0, 0, 0x8000},
+			{0, 0, 0, 0},
+		},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	want := newDungeon()
+	buf := &bytes.Buffer{}
+	if err := dun.Encode(buf, want); err != nil {
+		t.Fatalf("unable to encode DUN file; %v", err)
+	}
+	data := buf.Bytes()
+	if got, want := len(data), 4+2*2+2*(2*8); got != want {
+		t.Fatalf("DUN file size mismatch; expected %d, got %d", want, got)
+	}
+	got, err := dun.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unable to decode DUN file; %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dungeon mismatch; expected %+v, got %+v", want, got)
+	}
+
+	// Truncated layers.
+	for _, n := range []int{3, 6, 4 + 2*2 + 5, len(data) - 1} {
+		if _, err := dun.Decode(bytes.NewReader(data[:n])); err == nil {
+			t.Errorf("expected error for DUN file truncated to %d bytes, got nil", n)
+		}
+	}
+	// Invalid dimensions.
+	for _, d := range []*dun.Dungeon{{Width: -1, Height: 1}, {Width: 1, Height: -1}, {Width: 0x10000, Height: 1}} {
+		if err := dun.Encode(ioutil.Discard, d); err == nil {
+			t.Errorf("expected error for dungeon dimensions %dx%d, got nil", d.Width, d.Height)
+		}
+	}
+}
+
+func TestParseFS(t *testing.T) {
+	want := newDungeon()
+	buf := &bytes.Buffer{}
+	if err := dun.Encode(buf, want); err != nil {
+		t.Fatalf("unable to encode DUN file; %v", err)
+	}
+	// Truncate the last entry of the monster layer, which is zero.
+	data := buf.Bytes()[:buf.Len()-2]
+	fsys := fstest.MapFS{
+		"levels/l1data/banner2.dun": {Data: data},
+		"levels/l1data/banner1.dun": {Data: data},
+	}
+	got, err := dun.ParseFS(fsys, "levels/l1data/banner2.dun")
+	if err != nil {
+		t.Fatalf("unable to parse DUN file; %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dungeon mismatch; expected %+v, got %+v", want, got)
+	}
+	if _, err := dun.ParseFS(fsys, "levels/l1data/banner1.dun"); err == nil {
+		t.Errorf("expected error for truncated DUN file, got nil")
+	}
+	if _, err := dun.ParseFS(fsys, "levels/l1data/missing.dun"); err == nil {
+		t.Errorf("expected error for missing DUN file, got nil")
+	}
+}
@@ -28,13 +28,17 @@ package min
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
-	"fmt"
 	"image"
 	"image/draw"
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -57,25 +61,85 @@ type Block struct {
 }
 
 // Parse parses the given MIN file and returns its dungeon piece definitions.
+//
+// The number of blocks per dungeon piece is determined by the file name for the
+// MIN files of the original game, and inferred from the file contents and the
+// frame count of the corresponding level CEL file (e.g. "l1.cel" for "l1.min")
+// otherwise.
 func Parse(path string) ([]DPiece, error) {
-	// Open file for reading.
-	fr, err := os.Open(path)
+	// Read file contents.
+	buf, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	defer fr.Close()
-	br := bufio.NewReader(fr)
+	return parse(os.DirFS(filepath.Dir(path)), filepath.Base(path), buf)
+}
 
-	// Allocate block buffer.
+// ParseFS parses the named MIN file of fsys and returns its dungeon piece
+// definitions.
+func ParseFS(fsys fs.FS, name string) ([]DPiece, error) {
+	// Read file contents.
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return parse(fsys, name, buf)
+}
+
+// parse parses the given contents of the named MIN file of fsys and returns its
+// dungeon pieces.
+func parse(fsys fs.FS, name string, buf []byte) ([]DPiece, error) {
+	// Determine number of blocks per dungeon piece.
 	var nblocks int
-	switch name := filepath.Base(path); name {
+	switch path.Base(name) {
 	case "l1.min", "l2.min", "l3.min":
 		nblocks = 10
 	case "l4.min", "town.min":
 		nblocks = 16
 	default:
-		panic(fmt.Errorf("min.Parse: support for MIN file %q not yet implemented", name))
+		nframes, err := levelFrameCount(fsys, name)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		nblocks, err = InferNBlocks(buf, nframes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to determine number of blocks per dungeon piece of MIN file %q", name)
+		}
+	}
+
+	// Decode dungeon pieces.
+	return Decode(bytes.NewReader(buf), nblocks)
+}
+
+// levelFrameCount returns the number of frames of the level CEL file
+// corresponding to the named MIN file of fsys (e.g. "l1.cel" for "l1.min"), as
+// stored in the CEL header; or 0 if the level CEL file is not present.
+func levelFrameCount(fsys fs.FS, name string) (int, error) {
+	celName := strings.TrimSuffix(name, path.Ext(name)) + ".cel"
+	f, err := fsys.Open(celName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, errors.WithStack(err)
 	}
+	defer f.Close()
+	var nframes uint32
+	if err := binary.Read(f, binary.LittleEndian, &nframes); err != nil {
+		return 0, errors.Wrapf(err, "unable to read frame count of level CEL file %q", celName)
+	}
+	return int(nframes), nil
+}
+
+// Decode decodes the MIN file read from r, where each dungeon piece consists of
+// nblocks blocks (either 10 or 16), and returns its dungeon piece definitions.
+func Decode(r io.Reader, nblocks int) ([]DPiece, error) {
+	if nblocks != 10 && nblocks != 16 {
+		return nil, errors.Errorf("invalid number of blocks per dungeon piece; expected 10 or 16, got %d", nblocks)
+	}
+	br := bufio.NewReader(r)
+
+	// Allocate block buffer.
 	buf := make([]uint16, nblocks)
 
 	// Decode dungeon pieces.
@@ -91,11 +155,7 @@ func Parse(path string) ([]DPiece, error) {
 			Blocks: make([]Block, nblocks),
 		}
 		for i := range buf {
-			block := Block{
-				FrameNum:  int(buf[i] & 0x0FFF),
-				FrameType: int(buf[i] & 0x7000 >> 12),
-			}
-			dpiece.Blocks[i] = block
+			dpiece.Blocks[i] = decodeBlock(buf[i])
 		}
 		dpieces = append(dpieces, dpiece)
 	}
@@ -103,6 +163,123 @@ func Parse(path string) ([]DPiece, error) {
 	return dpieces, nil
 }
 
+// decodeBlock decodes the CEL frame number and frame type of the given block.
+func decodeBlock(x uint16) Block {
+	return Block{
+		FrameNum:  int(x & 0x0FFF),
+		FrameType: int(x & 0x7000 >> 12),
+	}
+}
+
+// Encode encodes the given dungeon piece definitions in MIN file format,
+// writing to w. Each dungeon piece must consist of the same number of blocks
+// (either 10 or 16).
+func Encode(w io.Writer, dpieces []DPiece) error {
+	bw := bufio.NewWriter(w)
+	nblocks := 0
+	for i, dpiece := range dpieces {
+		// Dungeon piece IDs are 1-based.
+		dpieceID := i + 1
+		if nblocks == 0 {
+			nblocks = len(dpiece.Blocks)
+			if nblocks != 10 && nblocks != 16 {
+				return errors.Errorf("invalid number of blocks of dungeon piece %d; expected 10 or 16, got %d", dpieceID, nblocks)
+			}
+		}
+		if len(dpiece.Blocks) != nblocks {
+			return errors.Errorf("mismatch between number of blocks of dungeon piece %d; expected %d, got %d", dpieceID, nblocks, len(dpiece.Blocks))
+		}
+		buf := make([]uint16, nblocks)
+		for blockNum, block := range dpiece.Blocks {
+			x, err := encodeBlock(block)
+			if err != nil {
+				return errors.Wrapf(err, "invalid block %d of dungeon piece %d", blockNum, dpieceID)
+			}
+			buf[blockNum] = x
+		}
+		if err := binary.Write(bw, binary.LittleEndian, buf); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// encodeBlock encodes the CEL frame number and frame type of the given block.
+func encodeBlock(block Block) (uint16, error) {
+	if block.FrameNum < 0 || block.FrameNum > 0x0FFF {
+		return 0, errors.Errorf("invalid frame number; expected 0 <= frameNum <= 0x0FFF, got %d", block.FrameNum)
+	}
+	if block.FrameType < 0 || block.FrameType > 7 {
+		return 0, errors.Errorf("invalid frame type; expected 0 <= frameType <= 7, got %d", block.FrameType)
+	}
+	return uint16(block.FrameType)<<12 | uint16(block.FrameNum), nil
+}
+
+// InferNBlocks infers the number of blocks per dungeon piece (either 10 or 16)
+// of the given MIN file contents. If nframes is non-zero, it specifies the
+// number of frames in the level CEL file.
+//
+// A candidate block count is valid if the file size is a multiple of the
+// dungeon piece size, and every dungeon piece of the candidate layout only
+// references frames within the level CEL file. If both block counts are valid,
+// the one placing the most floor triangles (frame type 2 and 3) in the bottom
+// row of the dungeon pieces is chosen, as floor triangles only occur at the
+// bottom of dungeon pieces. Should that not settle it either, 10 blocks are
+// chosen, as used by most levels of the original game.
+func InferNBlocks(buf []byte, nframes int) (int, error) {
+	if len(buf)%2 != 0 {
+		return 0, errors.Errorf("invalid MIN file size %d; expected multiple of 2", len(buf))
+	}
+	if len(buf)%20 != 0 && len(buf)%32 != 0 {
+		return 0, errors.Errorf("invalid MIN file size %d; expected multiple of 20 or 32", len(buf))
+	}
+	var candidates []int
+	var invalid error
+	for _, nblocks := range []int{10, 16} {
+		if len(buf)%(2*nblocks) != 0 {
+			continue
+		}
+		if nframes != 0 {
+			dpieces, err := Decode(bytes.NewReader(buf), nblocks)
+			if err != nil {
+				return 0, errors.WithStack(err)
+			}
+			if err := Validate(dpieces, nframes); err != nil {
+				invalid = errors.Wrapf(err, "invalid layout of %d blocks per dungeon piece", nblocks)
+				continue
+			}
+		}
+		candidates = append(candidates, nblocks)
+	}
+	switch len(candidates) {
+	case 0:
+		return 0, invalid
+	case 1:
+		return candidates[0], nil
+	}
+	// Break tie using the placement of floor triangles.
+	best, bestScore := candidates[0], -1
+	for _, nblocks := range candidates {
+		score := 0
+		for i := 0; i < len(buf)/2; i++ {
+			block := decodeBlock(binary.LittleEndian.Uint16(buf[2*i:]))
+			if block.FrameType == 2 || block.FrameType == 3 {
+				if i%nblocks >= nblocks-2 {
+					score++
+				}
+			}
+		}
+		// Strictly greater, so that 10 blocks are chosen on ties.
+		if score > bestScore {
+			best, bestScore = nblocks, score
+		}
+	}
+	return best, nil
+}
+
 // Image returns an image representation of the dungeon piece, where each non-
 // empty block corresponds to a CEL frame from levelFrames.
 //
@@ -145,3 +322,45 @@ func (dpiece DPiece) Image(levelFrames []image.Image) image.Image {
 	}
 	return img
 }
+
+// SafeImage returns an image representation of the dungeon piece, as returned
+// by Image. An error is returned if a block references a frame not present in
+// levelFrames.
+func (dpiece DPiece) SafeImage(levelFrames []image.Image) (image.Image, error) {
+	if err := dpiece.Validate(len(levelFrames)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return dpiece.Image(levelFrames), nil
+}
+
+// Validate checks that the blocks of the given dungeon pieces reference frames
+// within the given number of level frames, and that every dungeon piece has the
+// same number of blocks (either 10 or 16). The returned error names the dungeon
+// piece ID, block and frame number of the first invalid reference.
+func Validate(dpieces []DPiece, nframes int) error {
+	for i, dpiece := range dpieces {
+		// Dungeon piece IDs are 1-based.
+		dpieceID := i + 1
+		if n := len(dpiece.Blocks); n != 10 && n != 16 {
+			return errors.Errorf("invalid block count of dungeon piece %d; expected 10 or 16, got %d", dpieceID, n)
+		}
+		if n, want := len(dpiece.Blocks), len(dpieces[0].Blocks); n != want {
+			return errors.Errorf("block count mismatch of dungeon piece %d; expected %d, got %d", dpieceID, want, n)
+		}
+		if err := dpiece.Validate(nframes); err != nil {
+			return errors.Wrapf(err, "invalid dungeon piece %d", dpieceID)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the blocks of the dungeon piece reference frames within
+// the given number of level frames.
+func (dpiece DPiece) Validate(nframes int) error {
+	for blockNum, block := range dpiece.Blocks {
+		if block.FrameNum < 0 || block.FrameNum > nframes {
+			return errors.Errorf("invalid frame number %d of block %d; expected 0 <= frameNum <= %d", block.FrameNum, blockNum, nframes)
+		}
+	}
+	return nil
+}
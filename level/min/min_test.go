@@ -0,0 +1,155 @@
+package min_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/formats/internal/diabdat"
+	"github.com/sanctuary/formats/level/min"
+)
+
+func TestEncode(t *testing.T) {
+	// Skip test if "diabdat.mpq" is not present.
+	fsys := diabdat.FS(t)
+
+	golden := []string{
+		"levels/l1data/l1.min",
+		"levels/l2data/l2.min",
+		"levels/l3data/l3.min",
+		"levels/l4data/l4.min",
+		"levels/towndata/town.min",
+	}
+	for _, relMinPath := range golden {
+		want, err := fs.ReadFile(fsys, relMinPath)
+		if err != nil {
+			t.Errorf("%q: unable to read MIN file; %v", relMinPath, err)
+			continue
+		}
+		dpieces, err := min.ParseFS(fsys, relMinPath)
+		if err != nil {
+			t.Errorf("%q: unable to parse MIN file; %v", relMinPath, err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := min.Encode(buf, dpieces); err != nil {
+			t.Errorf("%q: unable to encode MIN file; %v", relMinPath, err)
+			continue
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("%q: MIN file contents mismatch after parse and encode", relMinPath)
+		}
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	golden := []struct {
+		block min.Block
+		ok    bool
+	}{
+		{block: min.Block{FrameNum: 0x0FFF, FrameType: 7}, ok: true},
+		{block: min.Block{FrameNum: 0x1000, FrameType: 1}, ok: false},
+		{block: min.Block{FrameNum: -1, FrameType: 1}, ok: false},
+		{block: min.Block{FrameNum: 1, FrameType: 8}, ok: false},
+	}
+	for _, g := range golden {
+		dpiece := min.DPiece{Blocks: make([]min.Block, 10)}
+		dpiece.Blocks[3] = g.block
+		err := min.Encode(ioutil.Discard, []min.DPiece{dpiece})
+		if g.ok && err != nil {
+			t.Errorf("%+v: unexpected error; %v", g.block, err)
+		}
+		if !g.ok && err == nil {
+			t.Errorf("%+v: expected error, got nil", g.block)
+		}
+	}
+}
+
+func TestInferNBlocks(t *testing.T) {
+	// 160 blocks are evenly divisible into both 16 dungeon pieces of 10 blocks
+	// and 10 dungeon pieces of 16 blocks.
+	const nblocksTotal = 160
+	for _, want := range []int{10, 16} {
+		// Place floor triangles in the bottom row of each dungeon piece.
+		raw := make([]uint16, nblocksTotal)
+		for i := range raw {
+			frameNum := uint16(i + 1)
+			frameType := uint16(1)
+			switch i % want {
+			case want - 2:
+				frameType = 2
+			case want - 1:
+				frameType = 3
+			}
+			raw[i] = frameType<<12 | frameNum
+		}
+		buf := &bytes.Buffer{}
+		if err := binary.Write(buf, binary.LittleEndian, raw); err != nil {
+			t.Fatal(err)
+		}
+		got, err := min.InferNBlocks(buf.Bytes(), nblocksTotal)
+		if err != nil {
+			t.Errorf("nblocks=%d: unable to infer number of blocks; %v", want, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("number of blocks mismatch; expected %d, got %d", want, got)
+		}
+		dpieces, err := min.Decode(bytes.NewReader(buf.Bytes()), got)
+		if err != nil {
+			t.Errorf("nblocks=%d: unable to decode MIN file; %v", want, err)
+			continue
+		}
+		if len(dpieces) != nblocksTotal/want {
+			t.Errorf("nblocks=%d: dungeon piece count mismatch; expected %d, got %d", want, nblocksTotal/want, len(dpieces))
+		}
+	}
+
+	// Tie; without floor triangles, both layouts are equally likely.
+	raw := make([]byte, 2*nblocksTotal)
+	raw[0] = 0x01
+	got, err := min.InferNBlocks(raw, 1)
+	if err != nil {
+		t.Errorf("tie: unable to infer number of blocks; %v", err)
+	} else if got != 10 {
+		t.Errorf("tie: number of blocks mismatch; expected 10, got %d", got)
+	}
+
+	// Invalid frame number.
+	raw[0] = 0x02
+	if _, err := min.InferNBlocks(raw, 1); err == nil {
+		t.Errorf("expected error for invalid frame number, got nil")
+	}
+
+	// Invalid file size.
+	if _, err := min.InferNBlocks(make([]byte, 2*7), 0); err == nil {
+		t.Errorf("expected error for invalid file size, got nil")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := min.DPiece{Blocks: make([]min.Block, 10)}
+	valid.Blocks[9].FrameNum = 2
+	invalid := min.DPiece{Blocks: make([]min.Block, 10)}
+	invalid.Blocks[3].FrameNum = 3
+	if err := min.Validate([]min.DPiece{valid, valid}, 2); err != nil {
+		t.Errorf("unexpected error for valid dungeon pieces; %v", err)
+	}
+	if err := min.Validate([]min.DPiece{valid, invalid}, 2); err == nil {
+		t.Errorf("expected error for out-of-range frame number, got nil")
+	} else if !strings.Contains(err.Error(), "dungeon piece 2") {
+		t.Errorf("expected error to name dungeon piece 2 (1-based), got %q", err)
+	}
+	short := min.DPiece{Blocks: make([]min.Block, 16)}
+	if err := min.Validate([]min.DPiece{valid, short}, 2); err == nil {
+		t.Errorf("expected error for block count mismatch, got nil")
+	}
+	frames := []image.Image{image.NewRGBA(image.Rect(0, 0, 32, 32))}
+	if _, err := invalid.SafeImage(frames); err == nil {
+		t.Errorf("expected error for out-of-range frame number, got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package render
+
+// The game shades dungeon pieces and sprites using light tables (see
+// cel.LightTables), one per light level, from fully lit (light level 0) to black
+// (light level 15). Each light source lights the dungeon pieces within its
+// radius, with the light level increasing (darkening) by distance, and every
+// dungeon piece is shaded by the brightest light level it receives.
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+)
+
+// maxLightRadius specifies the maximum radius of light sources.
+const maxLightRadius = 15
+
+// A Light is a light source of a scene.
+type Light struct {
+	// Dungeon piece coordinate of the light source.
+	X, Y int
+	// Radius of the light source, in dungeon pieces (1-15); e.g. 10 for the
+	// light of the player.
+	Radius int
+}
+
+// lightFalloff returns the light level at the given distance from a light
+// source of the given radius, where the distance is specified in eighths of a
+// dungeon piece (0-127). The light level increases linearly from 0 at the light
+// source to 15 at the radius.
+//
+//    level = int(15*dist/(8*(radius+1)) + 0.5)
+//
+// ref: MakeLightTable
+func lightFalloff(radius, dist int) int {
+	if dist > 8*(radius+1) {
+		return 15
+	}
+	fs := 15 * float64(dist) / (8 * float64(radius+1))
+	return int(fs + 0.5)
+}
+
+// LightGrid returns the light levels of a dungeon map of the given dimensions
+// in dungeon pieces, as lit by the given light sources, where grid[y][x]
+// specifies the light level of the dungeon piece coordinate (x, y). Dungeon
+// pieces outside the radius of every light source receive the light level
+// ambient (e.g. 15 for the dark dungeon levels of the game).
+//
+// ref: DoLighting
+func LightGrid(dw, dh int, lights []Light, ambient int) [][]int {
+	grid := make([][]int, dh)
+	for y := range grid {
+		grid[y] = make([]int, dw)
+		for x := range grid[y] {
+			grid[y][x] = ambient
+		}
+	}
+	for _, light := range lights {
+		radius := light.Radius
+		if radius < 0 {
+			radius = 0
+		}
+		if radius > maxLightRadius {
+			radius = maxLightRadius
+		}
+		for y := light.Y - radius; y <= light.Y+radius; y++ {
+			if y < 0 || y >= dh {
+				continue
+			}
+			for x := light.X - radius; x <= light.X+radius; x++ {
+				if x < 0 || x >= dw {
+					continue
+				}
+				dx, dy := x-light.X, y-light.Y
+				dist := int(8 * math.Sqrt(float64(dx*dx+dy*dy)))
+				if dist > 127 {
+					continue
+				}
+				if v := lightFalloff(radius, dist); v < grid[y][x] {
+					grid[y][x] = v
+				}
+			}
+		}
+	}
+	return grid
+}
+
+// Shade returns a copy of the given frame shaded using the provided light
+// table, where pal specifies the palette of the light table. The colour of each
+// opaque pixel is resolved from its palette index through the light table, and
+// transparent pixels are left transparent.
+func Shade(src *cel.IndexedImage, pal color.Palette, lightTable *cel.TransitionTable) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i, ok := src.IndexAt(x, y)
+			if !ok {
+				continue
+			}
+			dst.Set(x, y, pal[lightTable.Indices[i]])
+		}
+	}
+	return dst
+}
+
+// shade returns a copy of the given frame shaded using the provided light
+// table (see Shade). The frame must retain the palette indices of its pixels,
+// as decoded by the cel package.
+func shade(frame image.Image, pal color.Palette, lightTable *cel.TransitionTable) (image.Image, error) {
+	src, ok := frame.(*cel.IndexedImage)
+	if !ok {
+		return nil, errors.Errorf("unable to shade frame of type %T; expected *cel.IndexedImage", frame)
+	}
+	return Shade(src, pal, lightTable), nil
+}
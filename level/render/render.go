@@ -0,0 +1,412 @@
+// Package render renders dungeon maps as isometric images.
+//
+// A dungeon map is a grid of tiles, where each tile consists of four dungeon
+// pieces (see package til), and each dungeon piece consists of a column of
+// blocks (see package min). The dungeon pieces of the map are drawn back to
+// front, so that walls and other tall dungeon pieces overlap the dungeon pieces
+// behind them.
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/sol"
+	"github.com/sanctuary/formats/level/til"
+)
+
+// Dimensions of the floor of a dungeon piece.
+const (
+	floorWidth  = 64
+	floorHeight = 32
+)
+
+// A Level holds the graphics of a level type (e.g. cathedral), as required to
+// render dungeon maps of the level type.
+type Level struct {
+	// Level type name; e.g. "l1".
+	Name string
+	// Tile definitions of the TIL file.
+	Tiles []til.Tile
+	// Dungeon pieces of the MIN file.
+	DPieces []min.DPiece
+	// Level frames of the CEL file.
+	Frames []image.Image
+	// Palette used to decode the level frames.
+	Pal color.Palette
+	// Dungeon piece properties of the SOL file; or nil if not present.
+	Props []sol.Props
+	// Special frames of the special CEL file (e.g. "l1s.cel"); or nil if the
+	// level type has no special frames.
+	Specials []image.Image
+	// Draw special frames (e.g. arches and door frames) on top of the dungeon
+	// pieces they belong to.
+	DrawSpecials bool
+}
+
+// Load loads the graphics of the given level type from the extracted
+// "diabdat.mpq" directory, using colours from the provided palette. The level
+// type name is one of "l1", "l2", "l3", "l4" or "town".
+//
+// The special frames of the level type are loaded if present, and drawn by
+// default.
+func Load(mpqDir, name string, pal color.Palette) (*Level, error) {
+	return LoadFS(os.DirFS(mpqDir), name, pal)
+}
+
+// LoadFS loads the graphics of the given level type from the game assets of
+// fsys, using colours from the provided palette (see Load).
+func LoadFS(fsys fs.FS, name string, pal color.Palette) (*Level, error) {
+	levelDir := path.Join("levels", name+"data")
+	tiles, err := til.ParseFS(fsys, path.Join(levelDir, name+".til"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dpieces, err := min.ParseFS(fsys, path.Join(levelDir, name+".min"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	frames, err := cel.DecodeAllFS(fsys, path.Join(levelDir, name+".cel"), pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	level := &Level{
+		Name:    name,
+		Tiles:   tiles,
+		DPieces: dpieces,
+		Frames:  frames,
+		Pal:     pal,
+	}
+	// Dungeon piece properties and special frames are optional.
+	props, err := sol.ParseFS(fsys, path.Join(levelDir, name+".sol"))
+	switch {
+	case err == nil:
+		level.Props = props
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, errors.WithStack(err)
+	}
+	if relPath, ok := specialCels[name]; ok {
+		specials, err := cel.DecodeAllFS(fsys, relPath, pal)
+		switch {
+		case err == nil:
+			level.Specials = specials
+			level.DrawSpecials = true
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, errors.WithStack(err)
+		}
+	}
+	return level, nil
+}
+
+// A Scene specifies the contents of a dungeon map to render.
+type Scene struct {
+	// Tile grid, where Tiles[y][x] specifies the tile ID at the tile coordinate
+	// (x, y). Tile IDs are 1-based indices into the tiles of the level, and 0
+	// specifies the absence of a tile.
+	//
+	// The tile coordinate x increases towards the bottom-right and y towards
+	// the bottom-left, as illustrated below.
+	//
+	//           (0,0)
+	//        (0,1) (1,0)
+	//     (0,2) (1,1) (2,0)
+	Tiles [][]uint16
+	// Transparency regions, where Trans[y][x] specifies the transparency
+	// region of the dungeon piece coordinate (x, y), as stored in the
+	// transparency layer of DUN files; or nil if not present.
+	Trans [][]uint16
+	// Transparency regions drawn with transparent walls; i.e. the regions the
+	// player is considered to be in.
+	TransRegions []uint16
+	// Light tables used to shade the scene, one per light level (see
+	// cel.LightTables); or nil to render the scene fully lit. Shading operates
+	// on palette indices; the level frames, special frames and sprite frames
+	// must thus retain the palette indices of their pixels (see
+	// cel.IndexedImage).
+	LightTables []*cel.TransitionTable
+	// Light sources of the scene.
+	Lights []Light
+	// Light level of dungeon pieces outside the radius of every light source;
+	// e.g. 15 (black) for the dungeon levels of the game.
+	Ambient int
+	// Sprites placed on the dungeon pieces of the scene (see LoadSprites).
+	Sprites []Placement
+}
+
+// Render renders the dungeon map of the given tile grid. See Scene.Tiles for a
+// description of grid.
+func (level *Level) Render(grid [][]uint16) (*image.RGBA, error) {
+	return level.RenderScene(&Scene{Tiles: grid})
+}
+
+// RenderScene renders the dungeon map of the given scene.
+func (level *Level) RenderScene(scene *Scene) (*image.RGBA, error) {
+	// Determine dungeon piece IDs of the map.
+	grid := scene.Tiles
+	w, h := gridSize(grid)
+	if w == 0 || h == 0 {
+		return nil, errors.Errorf("invalid tile grid dimensions %dx%d", w, h)
+	}
+	dw, dh := 2*w, 2*h
+	dgrid := make([][]int, dh)
+	for dy := range dgrid {
+		dgrid[dy] = make([]int, dw)
+		for dx := range dgrid[dy] {
+			dgrid[dy][dx] = -1
+		}
+	}
+	for y, row := range grid {
+		for x, tileID := range row {
+			if tileID == 0 {
+				continue
+			}
+			if int(tileID) > len(level.Tiles) {
+				return nil, errors.Errorf("invalid tile ID %d at (%d, %d); expected 1 <= id <= %d", tileID, x, y, len(level.Tiles))
+			}
+			tile := level.Tiles[tileID-1]
+			dgrid[2*y][2*x] = int(tile.Top)
+			dgrid[2*y][2*x+1] = int(tile.Right)
+			dgrid[2*y+1][2*x] = int(tile.Left)
+			dgrid[2*y+1][2*x+1] = int(tile.Bottom)
+		}
+	}
+
+	// Level frames shaded by light level; each frame is shaded on first use.
+	shaded := make(map[int][]image.Image)
+	levelFrames := func(dpiece min.DPiece, light int) ([]image.Image, error) {
+		if light == -1 {
+			return level.Frames, nil
+		}
+		frames, ok := shaded[light]
+		if !ok {
+			frames = make([]image.Image, len(level.Frames))
+			shaded[light] = frames
+		}
+		for _, block := range dpiece.Blocks {
+			if block.FrameNum == 0 || frames[block.FrameNum-1] != nil {
+				continue
+			}
+			img, err := shade(level.Frames[block.FrameNum-1], level.Pal, scene.LightTables[light])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to shade level frame %d", block.FrameNum)
+			}
+			frames[block.FrameNum-1] = img
+		}
+		return frames, nil
+	}
+
+	// Render dungeon pieces.
+	type key struct {
+		dpieceID int
+		trans    bool
+		light    int
+	}
+	cache := make(map[key]image.Image)
+	dpieceImage := func(dpieceID int, trans bool, light int) (image.Image, error) {
+		k := key{dpieceID: dpieceID, trans: trans, light: light}
+		if img, ok := cache[k]; ok {
+			return img, nil
+		}
+		// Dungeon piece IDs are 1-based in error messages.
+		if dpieceID >= len(level.DPieces) {
+			return nil, errors.Errorf("invalid dungeon piece %d; expected <= %d", dpieceID+1, len(level.DPieces))
+		}
+		dpiece := level.DPieces[dpieceID]
+		if err := dpiece.Validate(len(level.Frames)); err != nil {
+			return nil, errors.Wrapf(err, "invalid dungeon piece %d", dpieceID+1)
+		}
+		// Shade the level frames before arranging them, as shading operates
+		// on palette indices.
+		frames, err := levelFrames(dpiece, light)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to shade dungeon piece %d", dpieceID+1)
+		}
+		var img image.Image
+		if trans {
+			img = transImage(dpiece, level.Props[dpieceID], frames)
+		} else {
+			img = dpiece.Image(frames)
+		}
+		cache[k] = img
+		return img, nil
+	}
+
+	// Determine transparency regions drawn with transparent walls.
+	transRegions := make(map[uint16]bool)
+	for _, region := range scene.TransRegions {
+		transRegions[region] = true
+	}
+	isTrans := func(dx, dy, dpieceID int) bool {
+		if dy >= len(scene.Trans) || dx >= len(scene.Trans[dy]) {
+			return false
+		}
+		if !transRegions[scene.Trans[dy][dx]] {
+			return false
+		}
+		return dpieceID < len(level.Props) && level.Props[dpieceID].Transparent
+	}
+
+	// Determine light levels of the map; or -1 if fully lit.
+	lightAt := func(dx, dy int) int { return -1 }
+	if len(scene.LightTables) > 0 {
+		if scene.Ambient < 0 || scene.Ambient >= len(scene.LightTables) {
+			return nil, errors.Errorf("invalid ambient light level %d; expected 0 <= level < %d", scene.Ambient, len(scene.LightTables))
+		}
+		lgrid := LightGrid(dw, dh, scene.Lights, scene.Ambient)
+		lightAt = func(dx, dy int) int {
+			if v := lgrid[dy][dx]; v < len(scene.LightTables) {
+				return v
+			}
+			return len(scene.LightTables) - 1
+		}
+	}
+	specialCache := make(map[[2]int]image.Image)
+	specialImage := func(frameNum, light int) (image.Image, error) {
+		k := [2]int{frameNum, light}
+		if img, ok := specialCache[k]; ok {
+			return img, nil
+		}
+		img := level.Specials[frameNum-1]
+		if light != -1 {
+			var err error
+			img, err = shade(img, level.Pal, scene.LightTables[light])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to shade special frame %d", frameNum)
+			}
+		}
+		specialCache[k] = img
+		return img, nil
+	}
+
+	// Determine sprites placed on each dungeon piece, in order of placement.
+	placed := make(map[image.Point][]Placement)
+	for _, p := range scene.Sprites {
+		if p.X < 0 || p.X >= dw || p.Y < 0 || p.Y >= dh {
+			return nil, errors.Errorf("invalid sprite position (%d, %d); expected within %dx%d", p.X, p.Y, dw, dh)
+		}
+		if p.FrameNum < 0 || p.FrameNum >= len(p.Sprite.Frames) {
+			return nil, errors.Errorf("invalid frame number %d of sprite at (%d, %d); expected < %d", p.FrameNum, p.X, p.Y, len(p.Sprite.Frames))
+		}
+		pt := image.Pt(p.X, p.Y)
+		placed[pt] = append(placed[pt], p)
+	}
+	type frameKey struct {
+		sprite   *cel.Sprite
+		frameNum int
+		light    int
+	}
+	frameCache := make(map[frameKey]image.Image)
+	frameImage := func(p Placement, light int) (image.Image, error) {
+		k := frameKey{sprite: p.Sprite, frameNum: p.FrameNum, light: light}
+		if img, ok := frameCache[k]; ok {
+			return img, nil
+		}
+		img := p.Sprite.Frames[p.FrameNum]
+		if light != -1 {
+			var err error
+			img, err = shade(img, level.Pal, scene.LightTables[light])
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to shade frame %d of sprite at (%d, %d)", p.FrameNum, p.X, p.Y)
+			}
+		}
+		frameCache[k] = img
+		return img, nil
+	}
+
+	// The height of a dungeon piece extends above its floor.
+	dpieceHeight := floorHeight
+	if len(level.DPieces) > 0 {
+		dpieceHeight = floorHeight / 2 * len(level.DPieces[0].Blocks)
+	}
+	width := (dw + dh) * floorWidth / 2
+	height := (dw+dh-2)*floorHeight/2 + dpieceHeight
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Determine special frames of the map.
+	var sgrid [][]int
+	if level.DrawSpecials {
+		sgrid = specialGrid(level.Name, dgrid)
+	}
+
+	// Draw dungeon pieces back to front; one diagonal row at the time, as
+	// dungeon pieces of the same diagonal row never overlap.
+	for sum := 0; sum < dw+dh-1; sum++ {
+		for dx := 0; dx < dw; dx++ {
+			dy := sum - dx
+			if dy < 0 || dy >= dh {
+				continue
+			}
+			dpieceID := dgrid[dy][dx]
+			if dpieceID == -1 {
+				continue
+			}
+			light := lightAt(dx, dy)
+			src, err := dpieceImage(dpieceID, isTrans(dx, dy, dpieceID), light)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to render dungeon piece at (%d, %d)", dx, dy)
+			}
+			// Bottom-left corner of the dungeon piece.
+			x := (dx - dy + dh - 1) * floorWidth / 2
+			y := (dx+dy)*floorHeight/2 + dpieceHeight
+			bounds := src.Bounds()
+			dr := image.Rect(x, y-bounds.Dy(), x+bounds.Dx(), y)
+			draw.Draw(dst, dr, src, bounds.Min, draw.Over)
+
+			// Draw sprites placed on the dungeon piece.
+			for _, p := range placed[image.Pt(dx, dy)] {
+				frame, err := frameImage(p, light)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				fr := p.Sprite.Bounds(p.FrameNum, image.Pt(x, y))
+				draw.Draw(dst, fr, frame, frame.Bounds().Min, draw.Over)
+			}
+
+			// Draw special frame on top of the dungeon piece.
+			if sgrid == nil || sgrid[dy][dx] == 0 {
+				continue
+			}
+			frameNum := sgrid[dy][dx]
+			if frameNum > len(level.Specials) {
+				return nil, errors.Errorf("invalid special frame number %d at (%d, %d); expected <= %d", frameNum, dx, dy, len(level.Specials))
+			}
+			special, err := specialImage(frameNum, light)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			sb := special.Bounds()
+			sr := image.Rect(x, y-sb.Dy(), x+sb.Dx(), y)
+			draw.Draw(dst, sr, special, sb.Min, draw.Over)
+		}
+	}
+	return dst, nil
+}
+
+// LevelName returns the level type name of the given level file path relative
+// to "diabdat.mpq"; e.g. "l1" for "levels/l1data/sklkng.dun".
+func LevelName(relPath string) (string, error) {
+	dir := filepath.Base(filepath.Dir(relPath))
+	if !strings.HasSuffix(dir, "data") || dir == "data" {
+		return "", errors.Errorf("unable to locate level type of %q", relPath)
+	}
+	return strings.TrimSuffix(dir, "data"), nil
+}
+
+// gridSize returns the width and height of the given tile grid.
+func gridSize(grid [][]uint16) (w, h int) {
+	for _, row := range grid {
+		if len(row) > w {
+			w = len(row)
+		}
+	}
+	return w, len(grid)
+}
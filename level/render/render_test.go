@@ -0,0 +1,323 @@
+package render_test
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/render"
+	"github.com/sanctuary/formats/level/sol"
+	"github.com/sanctuary/formats/level/til"
+)
+
+func TestRender(t *testing.T) {
+	// Level of a single 32x32 frame, used by the bottom-left block of a floor
+	// dungeon piece and by every block of a wall dungeon piece.
+	frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	floor := min.DPiece{Blocks: make([]min.Block, 10)}
+	floor.Blocks[8].FrameNum = 1
+	wall := min.DPiece{Blocks: make([]min.Block, 10)}
+	for i := range wall.Blocks {
+		wall.Blocks[i].FrameNum = 1
+	}
+	level := &render.Level{
+		Tiles: []til.Tile{
+			{Top: 0, Right: 0, Left: 0, Bottom: 0},
+			{Top: 1, Right: 0, Left: 0, Bottom: 0},
+		},
+		DPieces: []min.DPiece{floor, wall},
+		Frames:  []image.Image{frame},
+	}
+	grid := [][]uint16{
+		{2, 1},
+		{0, 1},
+	}
+	img, err := level.Render(grid)
+	if err != nil {
+		t.Fatalf("unable to render dungeon map; %v", err)
+	}
+	// 4x4 dungeon pieces, of 64x160 pixels each.
+	if got, want := img.Bounds(), image.Rect(0, 0, 256, 256); got != want {
+		t.Fatalf("bounds mismatch; expected %v, got %v", want, got)
+	}
+	// Top of the wall dungeon piece at (0, 0).
+	if _, _, _, a := img.At(96, 0).RGBA(); a == 0 {
+		t.Errorf("expected wall pixel at (96, 0)")
+	}
+	// Absent tile at (0, 1); left corner of the map.
+	if _, _, _, a := img.At(0, 200).RGBA(); a != 0 {
+		t.Errorf("expected transparent pixel at (0, 200)")
+	}
+
+	if _, err := level.Render([][]uint16{{3}}); err == nil {
+		t.Errorf("expected error for invalid tile ID, got nil")
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	// Level of a single tile, dungeon piece and frame, without SOL file and
+	// special CEL file.
+	cel := make([]byte, 4+8+32*32)
+	binary.LittleEndian.PutUint32(cel[0:], 1)         // nframes
+	binary.LittleEndian.PutUint32(cel[4:], 4+8)       // frameOffsets[0]
+	binary.LittleEndian.PutUint32(cel[8:], 4+8+32*32) // frameOffsets[1]
+	fsys := fstest.MapFS{
+		"levels/l1data/l1.til": {Data: make([]byte, 4*2)},
+		"levels/l1data/l1.min": {Data: make([]byte, 10*2)},
+		"levels/l1data/l1.cel": {Data: cel},
+	}
+	pal := make(color.Palette, 256)
+	for i := range pal {
+		pal[i] = color.RGBA{A: 0xFF}
+	}
+	level, err := render.LoadFS(fsys, "l1", pal)
+	if err != nil {
+		t.Fatalf("unable to load level; %+v", err)
+	}
+	if len(level.Tiles) != 1 || len(level.DPieces) != 1 || len(level.Frames) != 1 {
+		t.Errorf("level contents mismatch; expected 1 tile, dungeon piece and frame, got %d, %d and %d", len(level.Tiles), len(level.DPieces), len(level.Frames))
+	}
+	if level.Props != nil || level.Specials != nil || level.DrawSpecials {
+		t.Errorf("expected no dungeon piece properties or special frames")
+	}
+
+	// Required files are reported as errors.
+	delete(fsys, "levels/l1data/l1.min")
+	if _, err := render.LoadFS(fsys, "l1", pal); err == nil {
+		t.Errorf("expected error for missing MIN file, got nil")
+	}
+}
+
+func TestRenderSpecials(t *testing.T) {
+	// Level of an empty dungeon piece 11 (1-based), which is overlaid by special
+	// frame 2 in the cathedral.
+	dpieces := make([]min.DPiece, 11)
+	for i := range dpieces {
+		dpieces[i].Blocks = make([]min.Block, 10)
+	}
+	special := image.NewRGBA(image.Rect(0, 0, 64, 160))
+	draw.Draw(special, image.Rect(0, 0, 64, 8), image.NewUniform(color.White), image.ZP, draw.Src)
+	level := &render.Level{
+		Name:     "l1",
+		Tiles:    []til.Tile{{Top: 10, Right: 0, Left: 0, Bottom: 0}},
+		DPieces:  dpieces,
+		Specials: []image.Image{image.NewRGBA(image.Rect(0, 0, 64, 160)), special},
+	}
+	grid := [][]uint16{{1}}
+	for _, drawSpecials := range []bool{false, true} {
+		level.DrawSpecials = drawSpecials
+		img, err := level.Render(grid)
+		if err != nil {
+			t.Fatalf("unable to render dungeon map; %v", err)
+		}
+		// Top of the dungeon piece at (0, 0).
+		_, _, _, a := img.At(32, 0).RGBA()
+		if got := a != 0; got != drawSpecials {
+			t.Errorf("special frame presence mismatch with DrawSpecials=%v; got %v", drawSpecials, got)
+		}
+	}
+}
+
+func TestRenderScene(t *testing.T) {
+	// Level of a single transparent wall dungeon piece with a transparent left
+	// wall.
+	frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	wall := min.DPiece{Blocks: make([]min.Block, 10)}
+	for i := range wall.Blocks {
+		wall.Blocks[i].FrameNum = 1
+	}
+	level := &render.Level{
+		Tiles:   []til.Tile{{Top: 0, Right: 0, Left: 0, Bottom: 0}},
+		DPieces: []min.DPiece{wall},
+		Frames:  []image.Image{frame},
+		Props:   []sol.Props{{Transparent: true, TransparentLeft: true}},
+	}
+	// The left dungeon piece at (0, 1) is located in transparency region 1, and
+	// is the only dungeon piece covering the left edge (x 0-31) of the map.
+	trans := [][]uint16{
+		{0, 0},
+		{1, 0},
+	}
+	golden := []struct {
+		regions []uint16
+		want    bool
+	}{
+		{regions: nil, want: false},
+		{regions: []uint16{2}, want: false},
+		{regions: []uint16{1}, want: true},
+	}
+	for _, g := range golden {
+		scene := &render.Scene{
+			Tiles:        [][]uint16{{1}},
+			Trans:        trans,
+			TransRegions: g.regions,
+		}
+		img, err := level.RenderScene(scene)
+		if err != nil {
+			t.Fatalf("unable to render dungeon map; %v", err)
+		}
+		// Adjacent pixels of an upper block; one of which is omitted by the
+		// dither mask of transparent walls.
+		_, _, _, a1 := img.At(0, 40).RGBA()
+		_, _, _, a2 := img.At(1, 40).RGBA()
+		if got := a1 == 0 || a2 == 0; got != g.want {
+			t.Errorf("transparency mismatch of upper block with regions %v; expected %v, got %v", g.regions, g.want, got)
+		}
+		// Adjacent pixels of the upper half of the bottom-left block, which is
+		// left opaque by the mask of transparent left walls.
+		_, _, _, a1 = img.At(0, 150).RGBA()
+		_, _, _, a2 = img.At(1, 150).RGBA()
+		if a1 == 0 || a2 == 0 {
+			t.Errorf("expected opaque bottom-left block with regions %v", g.regions)
+		}
+	}
+}
+
+func TestRenderSceneLighting(t *testing.T) {
+	// Grey palette, ordered from bright to dark; with black at index 0.
+	pal := make(color.Palette, 256)
+	pal[0] = color.RGBA{A: 0xFF}
+	for i := 1; i < len(pal); i++ {
+		pal[i] = color.RGBA{R: uint8(255 - i), G: uint8(255 - i), B: uint8(255 - i), A: 0xFF}
+	}
+	// Duplicate the first colour of the second colour group in the first
+	// colour group, so that shading by colour would use the wrong light table
+	// row.
+	pal[15] = pal[16]
+	// Level of a single floor dungeon piece, using the first colour of the
+	// second colour group.
+	frame := cel.NewIndexedImage(image.Rect(0, 0, 32, 32), pal)
+	for i := range frame.Pix {
+		frame.Pix[i] = 16
+		frame.Mask.Pix[i] = 0xFF
+	}
+	floor := min.DPiece{Blocks: make([]min.Block, 10)}
+	floor.Blocks[8].FrameNum = 1
+	floor.Blocks[9].FrameNum = 1
+	level := &render.Level{
+		Tiles:   []til.Tile{{Top: 0, Right: 0, Left: 0, Bottom: 0}},
+		DPieces: []min.DPiece{floor},
+		Frames:  []image.Image{frame},
+		Pal:     pal,
+	}
+	// 8x8 tiles; 16x16 dungeon pieces.
+	grid := make([][]uint16, 8)
+	for y := range grid {
+		grid[y] = []uint16{1, 1, 1, 1, 1, 1, 1, 1}
+	}
+	scene := &render.Scene{
+		Tiles:       grid,
+		LightTables: cel.LightTables(false),
+		Lights:      []render.Light{{X: 0, Y: 0, Radius: 5}},
+		Ambient:     15,
+	}
+	img, err := level.RenderScene(scene)
+	if err != nil {
+		t.Fatalf("unable to render dungeon map; %v", err)
+	}
+	lgrid := render.LightGrid(16, 16, scene.Lights, scene.Ambient)
+	if lgrid[0][0] >= lgrid[0][3] || lgrid[0][3] >= lgrid[0][15] {
+		t.Errorf("expected light level to increase by distance; got %d, %d and %d", lgrid[0][0], lgrid[0][3], lgrid[0][15])
+	}
+	if got, want := lgrid[15][15], 15; got != want {
+		t.Errorf("light level mismatch outside of light radius; expected %d, got %d", want, got)
+	}
+	// Floors of the dungeon pieces at (0, 0), (3, 0) and (15, 15), not
+	// covered by the floors of neighbouring dungeon pieces.
+	golden := []struct {
+		pt   image.Point
+		want color.Color
+	}{
+		{pt: image.Pt(16*32, 160-20), want: pal[16+lgrid[0][0]]},
+		{pt: image.Pt(19*32, 3*16+160-20), want: pal[16+lgrid[0][3]]},
+		{pt: image.Pt(16*32, 30*16+160-16), want: pal[0]},
+	}
+	for _, g := range golden {
+		got := color.RGBAModel.Convert(img.At(g.pt.X, g.pt.Y))
+		if want := color.RGBAModel.Convert(g.want); got != want {
+			t.Errorf("colour mismatch at %v; expected %v, got %v", g.pt, want, got)
+		}
+	}
+}
+
+func TestRenderSceneSprites(t *testing.T) {
+	// Level of an empty dungeon piece and a wall dungeon piece, with the wall
+	// at the bottom of the tile.
+	frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	empty := min.DPiece{Blocks: make([]min.Block, 10)}
+	wall := min.DPiece{Blocks: make([]min.Block, 10)}
+	for i := range wall.Blocks {
+		wall.Blocks[i].FrameNum = 1
+	}
+	level := &render.Level{
+		Tiles:   []til.Tile{{Top: 0, Right: 0, Left: 0, Bottom: 1}},
+		DPieces: []min.DPiece{empty, wall},
+		Frames:  []image.Image{frame},
+	}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	spriteFrame := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(spriteFrame, spriteFrame.Bounds(), image.NewUniform(red), image.ZP, draw.Src)
+	sprite := &cel.Sprite{
+		Frames:  []image.Image{spriteFrame},
+		Anchors: []image.Point{{X: 0, Y: 0}},
+	}
+	golden := []struct {
+		// Dungeon piece coordinate of the sprite.
+		pt image.Point
+		// Sprite visible at (64, 150).
+		want bool
+	}{
+		// Behind the wall.
+		{pt: image.Pt(0, 0), want: false},
+		// On the wall.
+		{pt: image.Pt(1, 1), want: true},
+	}
+	for _, g := range golden {
+		scene := &render.Scene{
+			Tiles:   [][]uint16{{1}},
+			Sprites: []render.Placement{{X: g.pt.X, Y: g.pt.Y, Sprite: sprite}},
+		}
+		img, err := level.RenderScene(scene)
+		if err != nil {
+			t.Fatalf("unable to render dungeon map; %v", err)
+		}
+		got := color.RGBAModel.Convert(img.At(64, 150)) == red
+		if got != g.want {
+			t.Errorf("sprite visibility mismatch at %v; expected %v, got %v", g.pt, g.want, got)
+		}
+	}
+
+	scene := &render.Scene{
+		Tiles:   [][]uint16{{1}},
+		Sprites: []render.Placement{{X: 2, Y: 0, Sprite: sprite}},
+	}
+	if _, err := level.RenderScene(scene); err == nil {
+		t.Errorf("expected error for sprite outside of map, got nil")
+	}
+}
+
+func TestLoadSpritesFS(t *testing.T) {
+	// Objects and monsters without known graphics are skipped.
+	d := &dun.Dungeon{
+		Width:    1,
+		Height:   1,
+		Objects:  [][]uint16{{9, 0}, {0, 0}},
+		Monsters: [][]uint16{{0, 54}, {0, 0}},
+	}
+	placements, err := render.LoadSpritesFS(fstest.MapFS{}, d, nil)
+	if err != nil {
+		t.Fatalf("unable to load sprites; %v", err)
+	}
+	if len(placements) != 0 {
+		t.Errorf("placement count mismatch; expected 0, got %d", len(placements))
+	}
+}
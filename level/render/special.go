@@ -0,0 +1,107 @@
+package render
+
+// Special frames are drawn on top of specific dungeon pieces (and any objects,
+// monsters and players placed on them), so that arches and door frames occlude
+// what is behind them. The special frames of each level type are stored in a
+// separate CEL file (e.g. "levels/l1data/l1s.cel"), and each special frame has
+// the dimensions of a dungeon piece.
+
+// specialCels maps from level type name to the path of its special CEL file,
+// relative to "diabdat.mpq".
+var specialCels = map[string]string{
+	"l1": "levels/l1data/l1s.cel",
+	"l2": "levels/l2data/l2s.cel",
+}
+
+// specials maps from level type name to a map from 1-based dungeon piece ID to
+// 1-based special frame number.
+//
+// ref: DRLG_InitL1Vals, DRLG_InitL2Vals
+var specials = map[string]map[int]int{
+	"l1": {
+		11:  2,
+		12:  1,
+		71:  1,
+		211: 1,
+		249: 2,
+		255: 4,
+		259: 5,
+		321: 1,
+		325: 2,
+		331: 2,
+		341: 1,
+		344: 2,
+		418: 1,
+		421: 2,
+	},
+	"l2": {
+		13:  5,
+		17:  6,
+		178: 5,
+		541: 5,
+		542: 6,
+		551: 5,
+		553: 6,
+	},
+}
+
+// A specialSpan specifies special frames placed on the dungeon pieces following
+// a given dungeon piece, in the direction (dx, dy).
+type specialSpan struct {
+	// Direction of the span.
+	dx, dy int
+	// 1-based special frame numbers of the dungeon pieces following the given
+	// dungeon piece.
+	frames []int
+}
+
+// specialSpans maps from level type name to a map from 1-based dungeon piece ID
+// to special frames placed on the dungeon pieces following it; e.g. the frames
+// of doorways spanning multiple dungeon pieces.
+//
+// ref: DRLG_InitL2Vals
+var specialSpans = map[string]map[int]specialSpan{
+	"l2": {
+		132: {dx: 0, dy: 1, frames: []int{2, 1}},
+		135: {dx: 1, dy: 0, frames: []int{3, 4}},
+		139: {dx: 1, dy: 0, frames: []int{3, 4}},
+	},
+}
+
+// specialGrid returns the 1-based special frame numbers of the given grid of
+// 0-based dungeon piece IDs (or -1 for none) of the level type; or 0 if no
+// special frame is placed on the dungeon piece.
+func specialGrid(name string, dgrid [][]int) [][]int {
+	sgrid := make([][]int, len(dgrid))
+	for y := range dgrid {
+		sgrid[y] = make([]int, len(dgrid[y]))
+	}
+	m := specials[name]
+	spans := specialSpans[name]
+	for y, row := range dgrid {
+		for x, dpieceIdx := range row {
+			// Dungeon piece IDs of the game are 1-based.
+			dpieceID := dpieceIdx + 1
+			if frameNum, ok := m[dpieceID]; ok {
+				sgrid[y][x] = frameNum
+			}
+		}
+	}
+	// Spans override the special frames of the dungeon pieces they cover, as
+	// they are placed after in the game.
+	for y, row := range dgrid {
+		for x, dpieceIdx := range row {
+			span, ok := spans[dpieceIdx+1]
+			if !ok {
+				continue
+			}
+			for i, frameNum := range span.frames {
+				sx, sy := x+(i+1)*span.dx, y+(i+1)*span.dy
+				if sy < len(sgrid) && sx < len(sgrid[sy]) {
+					sgrid[sy][sx] = frameNum
+				}
+			}
+		}
+	}
+	return sgrid
+}
@@ -0,0 +1,217 @@
+package render
+
+// The object and monster layers of DUN files place objects (e.g. chests, levers
+// and books) and monsters on the dungeon pieces of quest levels. Their sprites
+// are drawn right after the dungeon piece they are placed on, so that walls in
+// front of them occlude them, and before the special frame of the dungeon
+// piece.
+
+import (
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/image/cel/monsters"
+	"github.com/sanctuary/formats/level/dun"
+)
+
+// A Placement is a sprite frame placed on a dungeon piece of a scene.
+type Placement struct {
+	// Dungeon piece coordinate of the sprite.
+	X, Y int
+	// Sprite to draw.
+	Sprite *cel.Sprite
+	// 0-based frame number of the sprite to draw.
+	FrameNum int
+}
+
+// An objectGfx specifies the graphics of an object placed by DUN files.
+type objectGfx struct {
+	// Object name.
+	name string
+	// CEL file path, relative to "diabdat.mpq".
+	relPath string
+	// 1-based frame number of the object as first placed.
+	frameNum int
+}
+
+// objects maps from object ID of DUN files to the graphics of the object.
+//
+// NOTE: Only the objects of the quest levels are covered; objects with IDs not
+// present in the table are skipped with a logged warning by LoadSprites.
+//
+// ref: ObjTypeConv, AllObjects, ObjMasterLoadList
+var objects = map[uint16]objectGfx{
+	1:  {name: "lever", relPath: "objects/lever.cel", frameNum: 1},
+	2:  {name: "crucified skeleton 1", relPath: "objects/cruxsk1.cel", frameNum: 1},
+	3:  {name: "crucified skeleton 2", relPath: "objects/cruxsk2.cel", frameNum: 1},
+	4:  {name: "crucified skeleton 3", relPath: "objects/cruxsk3.cel", frameNum: 1},
+	5:  {name: "angel", relPath: "objects/angel.cel", frameNum: 1},
+	6:  {name: "banner (left)", relPath: "objects/banner.cel", frameNum: 2},
+	7:  {name: "banner (middle)", relPath: "objects/banner.cel", frameNum: 1},
+	8:  {name: "banner (right)", relPath: "objects/banner.cel", frameNum: 3},
+	14: {name: "book (left)", relPath: "objects/book2.cel", frameNum: 1},
+	15: {name: "book (right)", relPath: "objects/book2.cel", frameNum: 4},
+	16: {name: "burning cross", relPath: "objects/burncros.cel", frameNum: 1},
+	18: {name: "candle 1", relPath: "objects/candle2.cel", frameNum: 1},
+	19: {name: "candle 2", relPath: "objects/candle2.cel", frameNum: 1},
+	21: {name: "cauldron", relPath: "objects/cauldren.cel", frameNum: 1},
+	30: {name: "flame hole", relPath: "objects/flame1.cel", frameNum: 1},
+	36: {name: "magic circle 1", relPath: "objects/mcirl.cel", frameNum: 1},
+	37: {name: "magic circle 2", relPath: "objects/mcirl.cel", frameNum: 3},
+	38: {name: "skull fire", relPath: "objects/skulfire.cel", frameNum: 1},
+	39: {name: "skull pile", relPath: "objects/skulpile.cel", frameNum: 1},
+	40: {name: "skull stick 1", relPath: "objects/skulstik.cel", frameNum: 1},
+	41: {name: "skull stick 2", relPath: "objects/skulstik.cel", frameNum: 2},
+	42: {name: "skull stick 3", relPath: "objects/skulstik.cel", frameNum: 3},
+	43: {name: "skull stick 4", relPath: "objects/skulstik.cel", frameNum: 4},
+	44: {name: "skull stick 5", relPath: "objects/skulstik.cel", frameNum: 5},
+	51: {name: "skull lever", relPath: "objects/switch4.cel", frameNum: 1},
+	55: {name: "tortured soul 1", relPath: "objects/tsoul.cel", frameNum: 1},
+	56: {name: "tortured soul 2", relPath: "objects/tsoul.cel", frameNum: 2},
+	57: {name: "tortured soul 3", relPath: "objects/tsoul.cel", frameNum: 3},
+	58: {name: "tortured soul 4", relPath: "objects/tsoul.cel", frameNum: 4},
+	59: {name: "tortured soul 5", relPath: "objects/tsoul.cel", frameNum: 5},
+	65: {name: "nude woman", relPath: "objects/nude2.cel", frameNum: 1},
+	70: {name: "tortured man 1", relPath: "objects/tnudem.cel", frameNum: 1},
+	71: {name: "tortured man 2", relPath: "objects/tnudem.cel", frameNum: 2},
+	72: {name: "tortured man 3", relPath: "objects/tnudem.cel", frameNum: 3},
+	73: {name: "tortured man 4", relPath: "objects/tnudem.cel", frameNum: 4},
+	74: {name: "tortured woman 1", relPath: "objects/tnudew.cel", frameNum: 1},
+	75: {name: "tortured woman 2", relPath: "objects/tnudew.cel", frameNum: 2},
+	76: {name: "tortured woman 3", relPath: "objects/tnudew.cel", frameNum: 3},
+	77: {name: "small chest", relPath: "objects/chest1.cel", frameNum: 1},
+	78: {name: "small chest", relPath: "objects/chest1.cel", frameNum: 1},
+	79: {name: "small chest", relPath: "objects/chest1.cel", frameNum: 1},
+	80: {name: "chest", relPath: "objects/chest2.cel", frameNum: 1},
+	81: {name: "chest", relPath: "objects/chest2.cel", frameNum: 1},
+	82: {name: "chest", relPath: "objects/chest2.cel", frameNum: 1},
+	83: {name: "large chest", relPath: "objects/chest3.cel", frameNum: 1},
+	84: {name: "large chest", relPath: "objects/chest3.cel", frameNum: 1},
+	85: {name: "large chest", relPath: "objects/chest3.cel", frameNum: 1},
+	91: {name: "pedestal", relPath: "objects/pedistl.cel", frameNum: 1},
+}
+
+// dunMonsters maps from 1-based monster ID of DUN files to the name of the
+// monster type (see monsters.Types); or the empty string if the monster type
+// has no graphics in "diabdat.mpq".
+//
+// ref: MonstConvTbl
+var dunMonsters = []string{
+	"Zombie", "Ghoul", "Rotting Carcass", "Black Death",
+	"Fallen One (spear)", "Carver (spear)", "Devil Kin (spear)", "Dark One (spear)",
+	"Skeleton", "Corpse Axe", "Burning Dead", "Horror",
+	"Fallen One (sword)", "Carver (sword)", "Devil Kin (sword)", "Dark One (sword)",
+	"Scavenger", "Plague Eater", "Shadow Beast", "Bone Gasher",
+	"Skeleton Archer", "Corpse Bow", "Burning Dead Archer", "Horror Archer",
+	"Skeleton Captain", "Corpse Captain", "Burning Dead Captain", "Horror Captain",
+	"Hidden", "Stalker", "Unseen", "Illusion Weaver",
+	"Flesh Clan (mace)", "Stone Clan (mace)", "Fire Clan (mace)", "Night Clan (mace)",
+	"Fiend", "Blink", "Gloom", "Familiar",
+	"Flesh Clan (bow)", "Stone Clan (bow)", "Fire Clan (bow)", "Night Clan (bow)",
+	"Acid Beast", "Poison Spitter", "Pit Beast", "Lava Maw",
+	"Skeleton King", "Overlord", "Mud Man", "Toad Demon",
+	// The wyrm monster types are omitted (see monsters.Types).
+	"Flayed One", "", "", "",
+	"", "Magma Demon", "Blood Stone", "Hell Stone",
+	"Lava Lord", "Horned Demon", "Mud Runner", "Frost Charger",
+	"Obsidian Lord", "Bone Demon", "Red Death", "Litch Demon",
+	"Undead Balrog", "", "", "",
+	"", "Incinerator", "Flame Lord", "Doom Fire",
+	"Hell Burner", "", "", "",
+	"", "Red Storm", "Storm Rider", "Storm Lord",
+	"Maelstorm", "Winged-Demon", "Gargoyle", "Blood Claw",
+	"Death Wing", "Slayer", "Guardian", "Vortex Lord",
+	"Balrog", "Cave Viper", "Fire Drake", "Gold Viper",
+	"Azure Drake", "Black Knight", "Doom Guard", "Steel Lord",
+	"Blood Knight", "Unraveler", "Hollow One", "Pain Master",
+	"Reality Weaver", "Succubus", "Snow Witch", "Hell Spawn",
+	"Soul Burner", "Counselor", "Magistrate", "Cabalist",
+	"Advocate", "", "The Dark Lord", "",
+	"Golem",
+}
+
+// monsterType returns the monster type of the given name.
+func monsterType(name string) (*monsters.Monster, error) {
+	for _, m := range monsters.Types {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return nil, errors.Errorf("unable to locate monster type %q", name)
+}
+
+// LoadSprites loads the sprites of the objects and monsters placed by the given
+// DUN file from the extracted "diabdat.mpq" directory, using colours from the
+// provided palette, and returns their placements as first entered. Monsters are
+// drawn standing and facing south. Objects and monsters without known graphics
+// are skipped with a logged warning.
+func LoadSprites(mpqDir string, d *dun.Dungeon, pal color.Palette) ([]Placement, error) {
+	return LoadSpritesFS(os.DirFS(mpqDir), d, pal)
+}
+
+// LoadSpritesFS loads the sprites of the objects and monsters placed by the
+// given DUN file from the game assets of fsys, using colours from the provided
+// palette (see LoadSprites).
+func LoadSpritesFS(fsys fs.FS, d *dun.Dungeon, pal color.Palette) ([]Placement, error) {
+	var placements []Placement
+	celSprites := make(map[string]*cel.Sprite)
+	for y, row := range d.Objects {
+		for x, id := range row {
+			if id == 0 {
+				continue
+			}
+			obj, ok := objects[id]
+			if !ok {
+				log.Printf("render: skipping unknown object ID %d at (%d, %d)", id, x, y)
+				continue
+			}
+			sprite, ok := celSprites[obj.relPath]
+			if !ok {
+				var err error
+				sprite, err = cel.DecodeSpriteFS(fsys, obj.relPath, pal)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				celSprites[obj.relPath] = sprite
+			}
+			if obj.frameNum > len(sprite.Frames) {
+				return nil, errors.Errorf("invalid frame number %d of %q; expected <= %d", obj.frameNum, path.Base(obj.relPath), len(sprite.Frames))
+			}
+			placements = append(placements, Placement{X: x, Y: y, Sprite: sprite, FrameNum: obj.frameNum - 1})
+		}
+	}
+	monsterSprites := make(map[string]*cel.Sprite)
+	for y, row := range d.Monsters {
+		for x, id := range row {
+			if id == 0 {
+				continue
+			}
+			if int(id) > len(dunMonsters) || dunMonsters[id-1] == "" {
+				log.Printf("render: skipping unknown monster ID %d at (%d, %d)", id, x, y)
+				continue
+			}
+			name := dunMonsters[id-1]
+			sprite, ok := monsterSprites[name]
+			if !ok {
+				m, err := monsterType(name)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				dirs, err := m.DecodeSpritesFS(fsys, monsters.Stand, pal)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				// Direction 0 faces south.
+				sprite = dirs[0]
+				monsterSprites[name] = sprite
+			}
+			placements = append(placements, Placement{X: x, Y: y, Sprite: sprite, FrameNum: 0})
+		}
+	}
+	return placements, nil
+}
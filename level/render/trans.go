@@ -0,0 +1,129 @@
+package render
+
+// When the player is behind a wall, the game draws the blocks of transparent
+// dungeon pieces (see sol.Props) using a dither mask, so that every other pixel
+// of the wall is omitted. Dungeon pieces are drawn transparent if they are
+// located within one of the transparency regions the player is in, as stored
+// in the transparency layer of DUN files.
+//
+// The upper blocks of transparent dungeon pieces are drawn using wallMask. The
+// bottom-left block is drawn using leftMask if the dungeon piece has a
+// transparent left wall, and the bottom-right block is drawn using rightMask if
+// the dungeon piece has a transparent right wall; otherwise, the bottom blocks
+// are drawn opaque.
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/sol"
+)
+
+// Dimensions of blocks.
+const (
+	blockWidth  = 32
+	blockHeight = 32
+)
+
+// A mask is a transparency mask of a block, with one row per line of the block,
+// starting at the bottom line. The most significant bit of each row corresponds
+// to the leftmost pixel of the line. Pixels of set bits are drawn, and pixels of
+// cleared bits are omitted.
+type mask [blockHeight]uint32
+
+// wallMask is the dither mask of the upper blocks of transparent dungeon pieces.
+//
+// ref: WallMask
+var wallMask = mask{
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+	0xAAAAAAAA, 0x55555555, 0xAAAAAAAA, 0x55555555,
+}
+
+// leftMask is the dither mask of the bottom-left block of transparent dungeon
+// pieces with a transparent left wall.
+//
+// ref: LeftMask
+var leftMask = mask{
+	0xAAAAAAAB, 0x5555555F, 0xAAAAAABF, 0x555555FF,
+	0xAAAAABFF, 0x55555FFF, 0xAAAABFFF, 0x5555FFFF,
+	0xAAABFFFF, 0x555FFFFF, 0xAABFFFFF, 0x55FFFFFF,
+	0xABFFFFFF, 0x5FFFFFFF, 0xBFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+}
+
+// rightMask is the dither mask of the bottom-right block of transparent dungeon
+// pieces with a transparent right wall.
+//
+// ref: RightMask
+var rightMask = mask{
+	0xEAAAAAAA, 0xF5555555, 0xFEAAAAAA, 0xFF555555,
+	0xFFEAAAAA, 0xFFF55555, 0xFFFEAAAA, 0xFFFF5555,
+	0xFFFFEAAA, 0xFFFFF555, 0xFFFFFEAA, 0xFFFFFF55,
+	0xFFFFFFEA, 0xFFFFFFF5, 0xFFFFFFFE, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+	0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF, 0xFFFFFFFF,
+}
+
+// alpha returns an alpha mask representation of the mask.
+func (m *mask) alpha() *image.Alpha {
+	img := image.NewAlpha(image.Rect(0, 0, blockWidth, blockHeight))
+	for i, row := range m {
+		y := blockHeight - 1 - i
+		for x := 0; x < blockWidth; x++ {
+			if row&(1<<uint(blockWidth-1-x)) != 0 {
+				img.SetAlpha(x, y, color.Alpha{A: 0xFF})
+			}
+		}
+	}
+	return img
+}
+
+// transImage returns an image representation of the given dungeon piece with
+// transparent walls, as drawn by the game when the player is behind the dungeon
+// piece. The dungeon piece must have been validated against levelFrames.
+func transImage(dpiece min.DPiece, props sol.Props, levelFrames []image.Image) image.Image {
+	wall, left, right := wallMask.alpha(), leftMask.alpha(), rightMask.alpha()
+	n := len(dpiece.Blocks)
+	width := blockWidth * 2
+	height := blockHeight * (n / 2)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for blockNum, block := range dpiece.Blocks {
+		if block.FrameNum == 0 {
+			continue
+		}
+		// Blocks are stored from the top; the last two blocks are the
+		// bottom-left and bottom-right blocks of the dungeon piece.
+		var m *image.Alpha
+		switch {
+		case blockNum < n-2:
+			m = wall
+		case blockNum == n-2 && props.TransparentLeft:
+			m = left
+		case blockNum == n-1 && props.TransparentRight:
+			m = right
+		}
+		frame := levelFrames[block.FrameNum-1]
+		x := blockWidth * (blockNum % 2)
+		y := blockHeight * (blockNum / 2)
+		dr := image.Rect(x, y, x+blockWidth, y+blockHeight)
+		if m == nil {
+			draw.Draw(img, dr, frame, image.ZP, draw.Src)
+			continue
+		}
+		draw.DrawMask(img, dr, frame, image.ZP, m, image.ZP, draw.Src)
+	}
+	return img
+}
@@ -0,0 +1,110 @@
+// Package sol provides access to SOL files.
+//
+// The SOL file of each level (e.g. "levels/l1data/l1.sol") specifies the
+// properties of the level's dungeon pieces, as defined by the MIN file of the
+// level (e.g. "levels/l1data/l1.min"); such as whether a dungeon piece is
+// solid, or blocks light or missiles.
+//
+// Below follows a pseudo-code description of the SOL file format.
+//
+//    // A SOL file consists of a sequence of dungeon piece properties, one per
+//    // dungeon piece of the corresponding MIN file.
+//    type SOL []Flags
+//
+//    // Flags is a bitfield of dungeon piece properties.
+//    //
+//    //    solid            := flags&0x01 != 0
+//    //    blockLight       := flags&0x02 != 0
+//    //    blockMissile     := flags&0x04 != 0
+//    //    transparent      := flags&0x08 != 0
+//    //    transparentLeft  := flags&0x10 != 0
+//    //    transparentRight := flags&0x20 != 0
+//    //    trap             := flags&0x80 != 0
+//    type Flags uint8
+package sol
+
+import (
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Props specifies the properties of a dungeon piece. The properties at index i
+// of a SOL file correspond to the dungeon piece at index i of the MIN file of
+// the same level.
+type Props struct {
+	// Solid dungeon pieces block movement.
+	Solid bool
+	// BlockLight dungeon pieces block light and line of sight.
+	BlockLight bool
+	// BlockMissile dungeon pieces block missiles.
+	BlockMissile bool
+	// Transparent dungeon pieces are drawn transparent when the player is
+	// behind them.
+	Transparent bool
+	// TransparentLeft dungeon pieces have a transparent left wall.
+	TransparentLeft bool
+	// TransparentRight dungeon pieces have a transparent right wall.
+	TransparentRight bool
+	// Trap dungeon pieces may hold traps (e.g. arrow launchers in walls).
+	Trap bool
+}
+
+// Dungeon piece property flags.
+const (
+	flagSolid            = 0x01
+	flagBlockLight       = 0x02
+	flagBlockMissile     = 0x04
+	flagTransparent      = 0x08
+	flagTransparentLeft  = 0x10
+	flagTransparentRight = 0x20
+	flagTrap             = 0x80
+)
+
+// Parse parses the given SOL file and returns its dungeon piece properties.
+func Parse(path string) ([]Props, error) {
+	// Open file for reading.
+	fr, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fr.Close()
+	return Decode(fr)
+}
+
+// ParseFS parses the named SOL file of fsys and returns its dungeon piece
+// properties.
+func ParseFS(fsys fs.FS, name string) ([]Props, error) {
+	// Open file for reading.
+	fr, err := fsys.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fr.Close()
+	return Decode(fr)
+}
+
+// Decode decodes the SOL file read from r, and returns its dungeon piece
+// properties.
+func Decode(r io.Reader) ([]Props, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	props := make([]Props, len(buf))
+	for i, flags := range buf {
+		props[i] = Props{
+			Solid:            flags&flagSolid != 0,
+			BlockLight:       flags&flagBlockLight != 0,
+			BlockMissile:     flags&flagBlockMissile != 0,
+			Transparent:      flags&flagTransparent != 0,
+			TransparentLeft:  flags&flagTransparentLeft != 0,
+			TransparentRight: flags&flagTransparentRight != 0,
+			Trap:             flags&flagTrap != 0,
+		}
+	}
+	return props, nil
+}
@@ -0,0 +1,31 @@
+package sol_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sanctuary/formats/level/sol"
+)
+
+func TestDecode(t *testing.T) {
+	buf := []byte{0x00, 0x01, 0x0D, 0x36, 0x80}
+	want := []sol.Props{
+		{},
+		{Solid: true},
+		{Solid: true, Transparent: true, BlockMissile: true},
+		{BlockLight: true, BlockMissile: true, TransparentLeft: true, TransparentRight: true},
+		{Trap: true},
+	}
+	got, err := sol.Decode(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("unable to decode SOL file; %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("dungeon piece count mismatch; expected %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dungeon piece %d mismatch; expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
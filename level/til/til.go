@@ -17,13 +17,13 @@
 //    //    \/\/       4
 //    //     \/
 //    type Tile struct {
-//       // Dungeon piece ID at the top of the tile.
+//       // Dungeon piece index at the top of the tile.
 //       Top uint16
-//       // Dungeon piece ID at the right of the tile.
+//       // Dungeon piece index at the right of the tile.
 //       Right uint16
-//       // Dungeon piece ID at the left of the tile.
+//       // Dungeon piece index at the left of the tile.
 //       Left uint16
-//       // Dungeon piece ID at the bottom of the tile.
+//       // Dungeon piece index at the bottom of the tile.
 //       Bottom uint16
 //    }
 package til
@@ -34,6 +34,7 @@ import (
 	"image"
 	"image/draw"
 	"io"
+	"io/fs"
 	"os"
 
 	"github.com/pkg/errors"
@@ -48,14 +49,17 @@ import (
 //    /\/\      3 2
 //    \/\/       4
 //     \/
+//
+// Dungeon pieces are referenced by 0-based index into the dungeon pieces of the
+// MIN file; i.e. the 1-based dungeon piece ID minus one.
 type Tile struct {
-	// Dungeon piece ID at the top of the tile.
+	// Dungeon piece index at the top of the tile.
 	Top uint16
-	// Dungeon piece ID at the right of the tile.
+	// Dungeon piece index at the right of the tile.
 	Right uint16
-	// Dungeon piece ID at the left of the tile.
+	// Dungeon piece index at the left of the tile.
 	Left uint16
-	// Dungeon piece ID at the bottom of the tile.
+	// Dungeon piece index at the bottom of the tile.
 	Bottom uint16
 }
 
@@ -67,7 +71,23 @@ func Parse(path string) ([]Tile, error) {
 		return nil, errors.WithStack(err)
 	}
 	defer fr.Close()
-	br := bufio.NewReader(fr)
+	return Decode(fr)
+}
+
+// ParseFS parses the named TIL file of fsys and returns its tile definitions.
+func ParseFS(fsys fs.FS, name string) ([]Tile, error) {
+	// Open file for reading.
+	fr, err := fsys.Open(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer fr.Close()
+	return Decode(fr)
+}
+
+// Decode decodes the TIL file read from r, and returns its tile definitions.
+func Decode(r io.Reader) ([]Tile, error) {
+	br := bufio.NewReader(r)
 
 	// Decode tiles.
 	var tiles []Tile
@@ -90,6 +110,21 @@ func Parse(path string) ([]Tile, error) {
 	return tiles, nil
 }
 
+// Encode encodes the given tile definitions in TIL file format, writing to w.
+func Encode(w io.Writer, tiles []Tile) error {
+	bw := bufio.NewWriter(w)
+	for _, tile := range tiles {
+		x := [4]uint16{tile.Top, tile.Right, tile.Left, tile.Bottom}
+		if err := binary.Write(bw, binary.LittleEndian, &x); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 // Image returns an image representation of the tile. The dungeon pieces are
 // arranged as illustrated below, forming a square:
 //
@@ -129,3 +164,55 @@ func (tile Tile) Image(dpieces []min.DPiece, levelFrames []image.Image) image.Im
 	draw.Draw(img, bounds.Add(ptBottom), bottom, image.ZP, draw.Over)
 	return img
 }
+
+// SafeImage returns an image representation of the tile, as returned by Image.
+// An error is returned if the tile references a dungeon piece not present in
+// dpieces, or if a dungeon piece references a frame not present in
+// levelFrames.
+func (tile Tile) SafeImage(dpieces []min.DPiece, levelFrames []image.Image) (image.Image, error) {
+	if err := tile.validate(dpieces); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, idx := range []uint16{tile.Top, tile.Right, tile.Left, tile.Bottom} {
+		if err := dpieces[idx].Validate(len(levelFrames)); err != nil {
+			// Dungeon piece IDs are 1-based.
+			return nil, errors.Wrapf(err, "invalid dungeon piece %d", idx+1)
+		}
+	}
+	return tile.Image(dpieces, levelFrames), nil
+}
+
+// Validate checks that the given tiles reference dungeon pieces present in
+// dpieces. The returned error names the tile ID, position (top, right, left or
+// bottom) and dungeon piece ID of the first invalid reference. Tile IDs and
+// dungeon piece IDs are 1-based.
+func Validate(tiles []Tile, dpieces []min.DPiece) error {
+	for i, tile := range tiles {
+		if err := tile.validate(dpieces); err != nil {
+			// Tile IDs are 1-based.
+			tileID := i + 1
+			return errors.Wrapf(err, "invalid tile %d", tileID)
+		}
+	}
+	return nil
+}
+
+// validate checks that the tile references dungeon pieces present in dpieces.
+func (tile Tile) validate(dpieces []min.DPiece) error {
+	refs := []struct {
+		pos string
+		idx uint16
+	}{
+		{pos: "top", idx: tile.Top},
+		{pos: "right", idx: tile.Right},
+		{pos: "left", idx: tile.Left},
+		{pos: "bottom", idx: tile.Bottom},
+	}
+	for _, ref := range refs {
+		if int(ref.idx) >= len(dpieces) {
+			// Dungeon piece IDs are 1-based.
+			return errors.Errorf("invalid dungeon piece %d at %s position; expected <= %d", int(ref.idx)+1, ref.pos, len(dpieces))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package til_test
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/sanctuary/formats/internal/diabdat"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/til"
+)
+
+func TestEncode(t *testing.T) {
+	// Skip test if "diabdat.mpq" is not present.
+	fsys := diabdat.FS(t)
+
+	golden := []string{
+		"levels/l1data/l1.til",
+		"levels/l2data/l2.til",
+		"levels/l3data/l3.til",
+		"levels/l4data/l4.til",
+		"levels/towndata/town.til",
+	}
+	for _, relTilPath := range golden {
+		want, err := fs.ReadFile(fsys, relTilPath)
+		if err != nil {
+			t.Errorf("%q: unable to read TIL file; %v", relTilPath, err)
+			continue
+		}
+		tiles, err := til.Decode(bytes.NewReader(want))
+		if err != nil {
+			t.Errorf("%q: unable to parse TIL file; %v", relTilPath, err)
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := til.Encode(buf, tiles); err != nil {
+			t.Errorf("%q: unable to encode TIL file; %v", relTilPath, err)
+			continue
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("%q: TIL file contents mismatch after parse and encode", relTilPath)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	want := []til.Tile{
+		{Top: 1, Right: 2, Left: 3, Bottom: 4},
+		{Top: 0x1234, Right: 0, Left: 0xFFFF, Bottom: 7},
+	}
+	buf := &bytes.Buffer{}
+	if err := til.Encode(buf, want); err != nil {
+		t.Fatalf("unable to encode TIL file; %v", err)
+	}
+	got, err := til.Decode(buf)
+	if err != nil {
+		t.Fatalf("unable to decode TIL file; %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("tile count mismatch; expected %d, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("tile %d mismatch; expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dpieces := make([]min.DPiece, 4)
+	for i := range dpieces {
+		dpieces[i].Blocks = make([]min.Block, 10)
+	}
+	tiles := []til.Tile{
+		{Top: 0, Right: 1, Left: 2, Bottom: 3},
+		{Top: 0, Right: 1, Left: 4, Bottom: 3},
+	}
+	if err := til.Validate(tiles[:1], dpieces); err != nil {
+		t.Errorf("unexpected error for valid tiles; %v", err)
+	}
+	if err := til.Validate(tiles, dpieces); err == nil {
+		t.Errorf("expected error for out-of-range dungeon piece ID, got nil")
+	} else if msg := err.Error(); !strings.Contains(msg, "tile 2") || !strings.Contains(msg, "dungeon piece 5") {
+		// Tile IDs and dungeon piece IDs are 1-based.
+		t.Errorf("expected error to name tile 2 and dungeon piece 5, got %q", msg)
+	}
+	if _, err := tiles[1].SafeImage(dpieces, nil); err == nil {
+		t.Errorf("expected error for out-of-range dungeon piece ID, got nil")
+	}
+	dpieces[2].Blocks[0].FrameNum = 1
+	if _, err := tiles[0].SafeImage(dpieces, nil); err == nil {
+		t.Errorf("expected error for out-of-range frame number, got nil")
+	} else if !strings.Contains(err.Error(), "dungeon piece 3") {
+		t.Errorf("expected error to name dungeon piece 3, got %q", err)
+	}
+}
@@ -0,0 +1,420 @@
+// Package tiled converts levels to and from the map formats of the Tiled map
+// editor.
+//
+// The tiles of a level type are exported as an isometric tileset (TSX file),
+// backed by an atlas image of the rendered tiles. The tile grid of a DUN file or
+// generated dungeon level is exported as an isometric map (TMX file), with the
+// tile grid as a tile layer, and the items, monsters and objects as object
+// layers. Edited maps may be imported back to DUN files.
+//
+// The tile IDs of the DUN file correspond directly to global tile IDs of the
+// map, as the tileset is the first and only tileset of the map.
+//
+// Items, monsters and objects are placed at the center of their dungeon piece
+// (at twice the resolution of the tile grid). Each is represented by a point
+// object, with the DUN ID stored in the "id" property. As Tiled has no notion of
+// layers of twice the resolution, the transparency layer of the DUN file is
+// stored in the "transparency" map property; as rows of comma-separated values.
+package tiled
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/til"
+)
+
+// Dimensions of the floor of a tile.
+const (
+	tileWidth  = 128
+	tileHeight = 64
+)
+
+// Number of tiles per row of tileset atlas images.
+const atlasColumns = 16
+
+// Names of the layers of exported maps.
+const (
+	tileLayerName    = "tiles"
+	itemLayerName    = "items"
+	monsterLayerName = "monsters"
+	objectLayerName  = "objects"
+	transPropName    = "transparency"
+)
+
+// A Tileset is a Tiled tileset (TSX file).
+type Tileset struct {
+	XMLName    xml.Name `xml:"tileset"`
+	Version    string   `xml:"version,attr"`
+	Name       string   `xml:"name,attr"`
+	TileWidth  int      `xml:"tilewidth,attr"`
+	TileHeight int      `xml:"tileheight,attr"`
+	TileCount  int      `xml:"tilecount,attr"`
+	Columns    int      `xml:"columns,attr"`
+	Image      Image    `xml:"image"`
+}
+
+// An Image is an image reference of a Tiled tileset.
+type Image struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+// A Map is a Tiled map (TMX file).
+type Map struct {
+	XMLName      xml.Name      `xml:"map"`
+	Version      string        `xml:"version,attr"`
+	Orientation  string        `xml:"orientation,attr"`
+	RenderOrder  string        `xml:"renderorder,attr"`
+	Width        int           `xml:"width,attr"`
+	Height       int           `xml:"height,attr"`
+	TileWidth    int           `xml:"tilewidth,attr"`
+	TileHeight   int           `xml:"tileheight,attr"`
+	NextObjectID int           `xml:"nextobjectid,attr"`
+	Properties   []Property    `xml:"properties>property,omitempty"`
+	Tilesets     []TilesetRef  `xml:"tileset"`
+	Layers       []Layer       `xml:"layer"`
+	ObjectGroups []ObjectGroup `xml:"objectgroup"`
+}
+
+// A TilesetRef is a reference to an external tileset of a Tiled map.
+type TilesetRef struct {
+	FirstGID int    `xml:"firstgid,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// A Layer is a tile layer of a Tiled map.
+type Layer struct {
+	Name   string `xml:"name,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Data   Data   `xml:"data"`
+}
+
+// Data holds the global tile IDs of a tile layer.
+type Data struct {
+	Encoding string `xml:"encoding,attr"`
+	Text     string `xml:",chardata"`
+}
+
+// An ObjectGroup is an object layer of a Tiled map.
+type ObjectGroup struct {
+	Name    string   `xml:"name,attr"`
+	Objects []Object `xml:"object"`
+}
+
+// An Object is a point object of an object layer.
+type Object struct {
+	ID         int        `xml:"id,attr"`
+	Name       string     `xml:"name,attr,omitempty"`
+	Type       string     `xml:"type,attr,omitempty"`
+	X          float64    `xml:"x,attr"`
+	Y          float64    `xml:"y,attr"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Point      *struct{}  `xml:"point"`
+}
+
+// A Property is a custom property of a Tiled map or object.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:"value,attr"`
+}
+
+// ExportTileset renders the given tiles into a tileset atlas image, and returns
+// the tileset and atlas image. The source specifies the path of the atlas image
+// relative to the TSX file.
+func ExportTileset(name, source string, tiles []til.Tile, dpieces []min.DPiece, levelFrames []image.Image) (*Tileset, image.Image, error) {
+	if len(tiles) == 0 {
+		return nil, nil, errors.Errorf("unable to export tileset %q; no tiles", name)
+	}
+	var imgs []image.Image
+	for i, tile := range tiles {
+		img, err := tile.SafeImage(dpieces, levelFrames)
+		if err != nil {
+			// Tile IDs are 1-based.
+			return nil, nil, errors.Wrapf(err, "invalid tile %d", i+1)
+		}
+		imgs = append(imgs, img)
+	}
+	size := imgs[0].Bounds().Size()
+	columns := atlasColumns
+	if len(imgs) < columns {
+		columns = len(imgs)
+	}
+	rows := (len(imgs) + columns - 1) / columns
+	atlas := image.NewRGBA(image.Rect(0, 0, columns*size.X, rows*size.Y))
+	for i, img := range imgs {
+		pt := image.Pt(i%columns*size.X, i/columns*size.Y)
+		dr := image.Rectangle{Min: pt, Max: pt.Add(size)}
+		draw.Draw(atlas, dr, img, img.Bounds().Min, draw.Src)
+	}
+	ts := &Tileset{
+		Version:    "1.2",
+		Name:       name,
+		TileWidth:  size.X,
+		TileHeight: size.Y,
+		TileCount:  len(imgs),
+		Columns:    columns,
+		Image: Image{
+			Source: source,
+			Width:  atlas.Bounds().Dx(),
+			Height: atlas.Bounds().Dy(),
+		},
+	}
+	return ts, atlas, nil
+}
+
+// ExportMap returns the isometric map of the given dungeon. The source specifies
+// the path of the TSX file of the tileset relative to the TMX file.
+func ExportMap(d *dun.Dungeon, source string) *Map {
+	m := &Map{
+		Version:      "1.2",
+		Orientation:  "isometric",
+		RenderOrder:  "right-down",
+		Width:        d.Width,
+		Height:       d.Height,
+		TileWidth:    tileWidth,
+		TileHeight:   tileHeight,
+		NextObjectID: 1,
+		Tilesets:     []TilesetRef{{FirstGID: 1, Source: source}},
+	}
+	m.Layers = []Layer{{
+		Name:   tileLayerName,
+		Width:  d.Width,
+		Height: d.Height,
+		Data:   Data{Encoding: "csv", Text: encodeCSV(d.Tiles)},
+	}}
+	groups := []struct {
+		name  string
+		typ   string
+		layer [][]uint16
+	}{
+		{name: itemLayerName, typ: "item", layer: d.Items},
+		{name: monsterLayerName, typ: "monster", layer: d.Monsters},
+		{name: objectLayerName, typ: "object", layer: d.Objects},
+	}
+	for _, group := range groups {
+		if group.layer == nil {
+			continue
+		}
+		g := ObjectGroup{Name: group.name}
+		for y, row := range group.layer {
+			for x, id := range row {
+				if id == 0 {
+					continue
+				}
+				obj := Object{
+					ID:   m.NextObjectID,
+					Name: fmt.Sprintf("%s %d", group.typ, id),
+					Type: group.typ,
+					// Center of the dungeon piece; each tile consists of 2x2
+					// dungeon pieces of tileHeight/2 in Tiled isometric object
+					// coordinates.
+					X:          (float64(x) + 0.5) * tileHeight / 2,
+					Y:          (float64(y) + 0.5) * tileHeight / 2,
+					Properties: []Property{{Name: "id", Type: "int", Value: strconv.Itoa(int(id))}},
+					Point:      &struct{}{},
+				}
+				m.NextObjectID++
+				g.Objects = append(g.Objects, obj)
+			}
+		}
+		m.ObjectGroups = append(m.ObjectGroups, g)
+	}
+	if d.Transparency != nil {
+		prop := Property{Name: transPropName, Value: encodeCSV(d.Transparency)}
+		m.Properties = append(m.Properties, prop)
+	}
+	return m
+}
+
+// ImportMap returns the dungeon of the given isometric map, as exported by
+// ExportMap.
+func ImportMap(m *Map) (*dun.Dungeon, error) {
+	if m.Orientation != "isometric" {
+		return nil, errors.Errorf("unsupported map orientation %q; expected isometric", m.Orientation)
+	}
+	if len(m.Tilesets) != 1 || m.Tilesets[0].FirstGID != 1 {
+		return nil, errors.Errorf("unsupported tilesets; expected one tileset with first global tile ID 1")
+	}
+	if m.Width <= 0 || m.Height <= 0 {
+		return nil, errors.Errorf("invalid map dimensions %dx%d; expected positive width and height", m.Width, m.Height)
+	}
+	d := &dun.Dungeon{
+		Width:  m.Width,
+		Height: m.Height,
+	}
+	for _, layer := range m.Layers {
+		if layer.Name != tileLayerName {
+			continue
+		}
+		if layer.Data.Encoding != "csv" {
+			return nil, errors.Errorf("unsupported encoding %q of tile layer; expected csv", layer.Data.Encoding)
+		}
+		tiles, err := decodeCSV(layer.Data.Text, m.Width, m.Height)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode tile layer")
+		}
+		d.Tiles = tiles
+	}
+	if d.Tiles == nil {
+		return nil, errors.Errorf("unable to locate tile layer %q", tileLayerName)
+	}
+	for _, group := range m.ObjectGroups {
+		var dst *[][]uint16
+		switch group.Name {
+		case itemLayerName:
+			dst = &d.Items
+		case monsterLayerName:
+			dst = &d.Monsters
+		case objectLayerName:
+			dst = &d.Objects
+		default:
+			continue
+		}
+		layer := newLayer(2*m.Width, 2*m.Height)
+		// Object ID per dungeon piece position, to detect overlapping objects.
+		placed := make(map[image.Point]int)
+		for _, obj := range group.Objects {
+			x := int(obj.X / (tileHeight / 2))
+			y := int(obj.Y / (tileHeight / 2))
+			if obj.X < 0 || obj.Y < 0 || x >= 2*m.Width || y >= 2*m.Height {
+				return nil, errors.Errorf("%s object %d at (%g, %g) outside of map", group.Name, obj.ID, obj.X, obj.Y)
+			}
+			pt := image.Pt(x, y)
+			if prev, ok := placed[pt]; ok {
+				return nil, errors.Errorf("%s objects %d and %d at same dungeon piece (%d, %d)", group.Name, prev, obj.ID, x, y)
+			}
+			placed[pt] = obj.ID
+			id, err := objectID(obj)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s object %d", group.Name, obj.ID)
+			}
+			layer[y][x] = id
+		}
+		*dst = layer
+	}
+	for _, prop := range m.Properties {
+		if prop.Name != transPropName {
+			continue
+		}
+		trans, err := decodeCSV(prop.Value, 2*m.Width, 2*m.Height)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to decode transparency layer")
+		}
+		d.Transparency = trans
+	}
+	return d, nil
+}
+
+// EncodeTileset encodes the given tileset in TSX file format, writing to w.
+func EncodeTileset(w io.Writer, ts *Tileset) error {
+	return encodeXML(w, ts)
+}
+
+// EncodeMap encodes the given map in TMX file format, writing to w.
+func EncodeMap(w io.Writer, m *Map) error {
+	return encodeXML(w, m)
+}
+
+// DecodeMap decodes the TMX file read from r.
+func DecodeMap(r io.Reader) (*Map, error) {
+	m := &Map{}
+	if err := xml.NewDecoder(r).Decode(m); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return m, nil
+}
+
+// encodeXML encodes the given value as an XML document, writing to w.
+func encodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.WithStack(err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(v); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// objectID returns the DUN ID of the given object.
+func objectID(obj Object) (uint16, error) {
+	for _, prop := range obj.Properties {
+		if prop.Name != "id" {
+			continue
+		}
+		id, err := strconv.ParseUint(prop.Value, 10, 16)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		return uint16(id), nil
+	}
+	return 0, errors.Errorf(`unable to locate "id" property`)
+}
+
+// encodeCSV encodes the given layer as rows of comma-separated values.
+func encodeCSV(layer [][]uint16) string {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\n")
+	for y, row := range layer {
+		for x, v := range row {
+			buf.WriteString(strconv.Itoa(int(v)))
+			if x != len(row)-1 || y != len(layer)-1 {
+				buf.WriteString(",")
+			}
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// decodeCSV decodes the given comma-separated values into a layer of the
+// specified dimensions.
+func decodeCSV(s string, w, h int) ([][]uint16, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+	if len(fields) != w*h {
+		return nil, errors.Errorf("value count mismatch; expected %d, got %d", w*h, len(fields))
+	}
+	layer := newLayer(w, h)
+	for i, field := range fields {
+		v, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Tiled stores flip flags in the high bits of global tile IDs.
+		const flipMask = 0xE0000000
+		v &^= flipMask
+		if v > 0xFFFF {
+			return nil, errors.Errorf("value %d at index %d out of range", v, i)
+		}
+		layer[i/w][i%w] = uint16(v)
+	}
+	return layer, nil
+}
+
+// newLayer returns a new layer of the given dimensions.
+func newLayer(w, h int) [][]uint16 {
+	layer := make([][]uint16, h)
+	for y := range layer {
+		layer[y] = make([]uint16, w)
+	}
+	return layer
+}
@@ -0,0 +1,107 @@
+package tiled_test
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/min"
+	"github.com/sanctuary/formats/level/til"
+	"github.com/sanctuary/formats/level/tiled"
+)
+
+func TestMap(t *testing.T) {
+	want := &dun.Dungeon{
+		Width:  2,
+		Height: 2,
+		Tiles: [][]uint16{
+			{1, 2},
+			{0, 3},
+		},
+		Items: [][]uint16{
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+		},
+		Monsters: [][]uint16{
+			{0, 0, 0, 0},
+			{0, 5, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0x8001},
+		},
+		Objects: [][]uint16{
+			{7, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+			{0, 0, 0, 0},
+		},
+		Transparency: [][]uint16{
+			{1, 1, 2, 2},
+			{1, 1, 2, 2},
+			{0, 0, 3, 3},
+			{0, 0, 3, 3},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := tiled.EncodeMap(buf, tiled.ExportMap(want, "l1.tsx")); err != nil {
+		t.Fatalf("unable to encode TMX file; %v", err)
+	}
+	m, err := tiled.DecodeMap(buf)
+	if err != nil {
+		t.Fatalf("unable to decode TMX file; %v", err)
+	}
+	got, err := tiled.ImportMap(m)
+	if err != nil {
+		t.Fatalf("unable to import map; %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dungeon mismatch after export and import; expected %+v, got %+v", want, got)
+	}
+}
+
+func TestImportMapInvalid(t *testing.T) {
+	d := &dun.Dungeon{
+		Width:    1,
+		Height:   1,
+		Tiles:    [][]uint16{{1}},
+		Monsters: [][]uint16{{5, 0}, {0, 0}},
+	}
+
+	// Invalid dimensions.
+	m := tiled.ExportMap(d, "l1.tsx")
+	m.Width = 0
+	if _, err := tiled.ImportMap(m); err == nil {
+		t.Errorf("expected error for invalid map dimensions, got nil")
+	}
+
+	// Objects at the same dungeon piece.
+	m = tiled.ExportMap(d, "l1.tsx")
+	group := &m.ObjectGroups[0]
+	obj := group.Objects[0]
+	obj.ID++
+	group.Objects = append(group.Objects, obj)
+	if _, err := tiled.ImportMap(m); err == nil {
+		t.Errorf("expected error for overlapping objects, got nil")
+	}
+}
+
+func TestTileset(t *testing.T) {
+	frame := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	dpieces := []min.DPiece{{Blocks: make([]min.Block, 10)}}
+	dpieces[0].Blocks[0].FrameNum = 1
+	tiles := make([]til.Tile, 20)
+	ts, atlas, err := tiled.ExportTileset("l1", "l1.png", tiles, dpieces, []image.Image{frame})
+	if err != nil {
+		t.Fatalf("unable to export tileset; %v", err)
+	}
+	// 128x192 pixels per tile, 16 tiles per row.
+	if got, want := atlas.Bounds(), image.Rect(0, 0, 16*128, 2*192); got != want {
+		t.Errorf("atlas bounds mismatch; expected %v, got %v", want, got)
+	}
+	if ts.TileCount != len(tiles) || ts.TileWidth != 128 || ts.TileHeight != 192 {
+		t.Errorf("tileset mismatch; got %+v", ts)
+	}
+}
@@ -0,0 +1,171 @@
+// Package town assembles the map of Tristram from its sector DUN files.
+//
+// The town map is composed of four sectors, each stored in a DUN file of its
+// own and placed at a fixed offset of the map, as illustrated below (with tile
+// coordinate x increasing towards the bottom-right and y towards the
+// bottom-left).
+//
+//                 sector4s
+//                  (0, 0)
+//
+//        sector3s          sector2s
+//        (0, 23)           (23, 0)
+//
+//                 sector1s
+//                 (23, 23)
+//
+// The assembled map is 48x48 tiles, or 96x96 dungeon pieces, and is rendered
+// using the graphics of the "town" level type.
+package town
+
+import (
+	"image"
+	"io/fs"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/render"
+)
+
+// Dimensions of the town map in tiles.
+const (
+	Width  = 48
+	Height = 48
+)
+
+// A Sector specifies the placement of a sector on the town map.
+type Sector struct {
+	// Path to the DUN file of the sector, relative to "diabdat.mpq".
+	RelPath string
+	// Tile coordinate of the top-left corner of the sector on the town map.
+	X, Y int
+}
+
+// Sectors lists the sectors of the town map, at the offsets used by the game
+// when loading the town (T_Pass3).
+var Sectors = []Sector{
+	{RelPath: "levels/towndata/sector1s.dun", X: 23, Y: 23},
+	{RelPath: "levels/towndata/sector2s.dun", X: 23, Y: 0},
+	{RelPath: "levels/towndata/sector3s.dun", X: 0, Y: 23},
+	{RelPath: "levels/towndata/sector4s.dun", X: 0, Y: 0},
+}
+
+// Parse parses the sector DUN files of the extracted "diabdat.mpq" directory,
+// and returns the assembled town map.
+func Parse(mpqDir string) (*dun.Dungeon, error) {
+	return ParseFS(os.DirFS(mpqDir))
+}
+
+// ParseFS parses the sector DUN files of the game assets of fsys, and returns
+// the assembled town map.
+func ParseFS(fsys fs.FS) (*dun.Dungeon, error) {
+	var sectors []*dun.Dungeon
+	for _, sector := range Sectors {
+		d, err := dun.ParseFS(fsys, sector.RelPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sectors = append(sectors, d)
+	}
+	return Assemble(sectors)
+}
+
+// Assemble assembles the town map from the given sectors, which are placed at
+// the offsets of the corresponding entries in Sectors. Optional layers (items,
+// monsters, objects and transparency) present in any of the sectors are
+// assembled alongside the tile grid.
+func Assemble(sectors []*dun.Dungeon) (*dun.Dungeon, error) {
+	if len(sectors) != len(Sectors) {
+		return nil, errors.Errorf("sector count mismatch; expected %d, got %d", len(Sectors), len(sectors))
+	}
+	town := &dun.Dungeon{
+		Width:  Width,
+		Height: Height,
+		Tiles:  newLayer(Width, Height),
+	}
+	for i, d := range sectors {
+		sector := Sectors[i]
+		if sector.X+d.Width > Width || sector.Y+d.Height > Height {
+			return nil, errors.Errorf("%q: sector of %dx%d tiles at (%d, %d) exceeds town map of %dx%d tiles", sector.RelPath, d.Width, d.Height, sector.X, sector.Y, Width, Height)
+		}
+		copyLayer(town.Tiles, d.Tiles, sector.X, sector.Y)
+		layers := []struct {
+			dst *[][]uint16
+			src [][]uint16
+		}{
+			{dst: &town.Items, src: d.Items},
+			{dst: &town.Monsters, src: d.Monsters},
+			{dst: &town.Objects, src: d.Objects},
+			{dst: &town.Transparency, src: d.Transparency},
+		}
+		for _, layer := range layers {
+			if layer.src == nil {
+				continue
+			}
+			if *layer.dst == nil {
+				*layer.dst = newLayer(2*Width, 2*Height)
+			}
+			copyLayer(*layer.dst, layer.src, 2*sector.X, 2*sector.Y)
+		}
+	}
+	return town, nil
+}
+
+// Render renders the town map of the extracted "diabdat.mpq" directory, using
+// the graphics and palette of the "town" level type.
+func Render(mpqDir string) (*image.RGBA, error) {
+	return RenderFS(os.DirFS(mpqDir))
+}
+
+// RenderFS renders the town map of the game assets of fsys, using the graphics
+// and palette of the "town" level type.
+func RenderFS(fsys fs.FS) (*image.RGBA, error) {
+	town, err := ParseFS(fsys)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return RenderMapFS(fsys, town)
+}
+
+// RenderMap renders the given assembled town map, using the graphics and
+// palette of the "town" level type of the extracted "diabdat.mpq" directory.
+func RenderMap(mpqDir string, town *dun.Dungeon) (*image.RGBA, error) {
+	return RenderMapFS(os.DirFS(mpqDir), town)
+}
+
+// RenderMapFS renders the given assembled town map, using the graphics and
+// palette of the "town" level type of the game assets of fsys.
+func RenderMapFS(fsys fs.FS, town *dun.Dungeon) (*image.RGBA, error) {
+	pal, err := cel.ParsePalFS(fsys, "levels/towndata/town.pal")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	level, err := render.LoadFS(fsys, "town", pal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	img, err := level.Render(town.Tiles)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return img, nil
+}
+
+// newLayer returns a new layer of the given dimensions.
+func newLayer(w, h int) [][]uint16 {
+	layer := make([][]uint16, h)
+	for y := range layer {
+		layer[y] = make([]uint16, w)
+	}
+	return layer
+}
+
+// copyLayer copies the src layer into dst, with the top-left corner of src
+// placed at (x, y).
+func copyLayer(dst, src [][]uint16, x, y int) {
+	for j, row := range src {
+		copy(dst[y+j][x:], row)
+	}
+}
@@ -0,0 +1,73 @@
+package town_test
+
+import (
+	"testing"
+
+	"github.com/sanctuary/formats/level/dun"
+	"github.com/sanctuary/formats/level/town"
+)
+
+func TestAssemble(t *testing.T) {
+	var sectors []*dun.Dungeon
+	for i, sector := range town.Sectors {
+		w, h := town.Width-sector.X, town.Height-sector.Y
+		if sector.X == 0 {
+			w = town.Sectors[0].X
+		}
+		if sector.Y == 0 {
+			h = town.Sectors[0].Y
+		}
+		d := &dun.Dungeon{Width: w, Height: h, Tiles: make([][]uint16, h)}
+		for y := range d.Tiles {
+			d.Tiles[y] = make([]uint16, w)
+			for x := range d.Tiles[y] {
+				d.Tiles[y][x] = uint16(i + 1)
+			}
+		}
+		sectors = append(sectors, d)
+	}
+	got, err := town.Assemble(sectors)
+	if err != nil {
+		t.Fatalf("unable to assemble town map; %v", err)
+	}
+	if got.Width != town.Width || got.Height != town.Height {
+		t.Fatalf("town map dimensions mismatch; expected %dx%d, got %dx%d", town.Width, town.Height, got.Width, got.Height)
+	}
+	// Every tile is covered by exactly one sector.
+	var covered [town.Height][town.Width]int
+	for i, sector := range town.Sectors {
+		for y := 0; y < sectors[i].Height; y++ {
+			for x := 0; x < sectors[i].Width; x++ {
+				covered[sector.Y+y][sector.X+x]++
+			}
+		}
+	}
+	for y, row := range covered {
+		for x, n := range row {
+			if n != 1 {
+				t.Errorf("tile (%d, %d) covered by %d sectors; expected 1", x, y, n)
+			}
+		}
+	}
+	for y, row := range got.Tiles {
+		for x, tileID := range row {
+			if tileID == 0 {
+				t.Errorf("tile (%d, %d) not covered by any sector", x, y)
+			}
+		}
+	}
+	for i, sector := range town.Sectors {
+		if got, want := got.Tiles[sector.Y][sector.X], uint16(i+1); got != want {
+			t.Errorf("%q: tile ID mismatch at (%d, %d); expected %d, got %d", sector.RelPath, sector.X, sector.Y, want, got)
+		}
+	}
+	if got.Items != nil {
+		t.Errorf("expected nil item layer, got %d rows", len(got.Items))
+	}
+
+	// Sector exceeding the town map.
+	sectors[0] = &dun.Dungeon{Width: town.Width, Height: 1, Tiles: [][]uint16{make([]uint16, town.Width)}}
+	if _, err := town.Assemble(sectors); err == nil {
+		t.Errorf("expected error for oversized sector, got nil")
+	}
+}
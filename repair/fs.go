@@ -0,0 +1,84 @@
+package repair
+
+import (
+	"bytes"
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+// FS returns a file system which provides the files of fsys, with the known
+// broken files repaired in memory as they are read.
+func FS(fsys fs.FS) fs.FS {
+	return &fixFS{fsys: fsys}
+}
+
+// fixFS is a file system which repairs the known broken files of the
+// underlying file system as they are read.
+type fixFS struct {
+	// Underlying file system.
+	fsys fs.FS
+}
+
+// Open opens the named file.
+func (f *fixFS) Open(name string) (fs.File, error) {
+	fix, ok := Lookup(name)
+	if !ok {
+		return f.fsys.Open(name)
+	}
+	fr, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fr.Close()
+	info, err := fr.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	data, err := f.repair(name, fix)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &file{info: fileInfo{FileInfo: info, size: int64(len(data))}, r: bytes.NewReader(data)}, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (f *fixFS) ReadFile(name string) ([]byte, error) {
+	fix, ok := Lookup(name)
+	if !ok {
+		return fs.ReadFile(f.fsys, name)
+	}
+	return f.repair(name, fix)
+}
+
+// repair reads and repairs the named file of the underlying file system.
+func (f *fixFS) repair(name string, fix Fix) ([]byte, error) {
+	data, err := fs.ReadFile(f.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	fixed, err := fix.Repair(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return fixed, nil
+}
+
+// fileInfo describes a repaired file.
+type fileInfo struct {
+	fs.FileInfo
+	// Size of the repaired file in bytes.
+	size int64
+}
+
+func (fi fileInfo) Size() int64 { return fi.size }
+
+// file is a repaired file opened for reading.
+type file struct {
+	info fileInfo
+	r    *bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Read(p []byte) (int, error) { return f.r.Read(p) }
+func (f *file) Close() error               { return nil }
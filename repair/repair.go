@@ -0,0 +1,165 @@
+// Package repair repairs the known broken files of "diabdat.mpq".
+//
+// The original "diabdat.mpq" archive contains three broken files. The two
+// broken CEL files are repaired by this package:
+//
+//    monsters/darkmage/dmagew.cl2  // invalid CL2 archive header
+//    monsters/unrav/unravw.cel     // invalid CEL archive header
+//
+// The third broken file, "levels/l1data/banner2.dun", ends in the middle of a
+// layer and is handled by dun.Parse and dun.ParseFS.
+//
+// Files which match the SHA-1 hash of the repaired file, or which validate
+// against their image config (e.g. files repaired by other tools), are returned
+// unchanged. Other files are repaired if they match the SHA-1 hash of the known
+// broken file, or if the hash of the known broken file is not yet recorded.
+// Repaired contents are validated before they are returned, and any variant of
+// the file which fails to validate after repair is reported as an error.
+//
+// See https://github.com/mewrnd/blizzconv/issues/2 for background.
+package repair
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sanctuary/formats/image/cel"
+)
+
+// A Fix specifies the repair of a known broken file.
+type Fix struct {
+	// File path, relative to "diabdat.mpq".
+	RelPath string
+	// SHA-1 hash of the known broken file, in hexadecimal; or empty if not yet
+	// recorded.
+	Broken string
+	// SHA-1 hash of the repaired file, in hexadecimal; or empty if not yet
+	// recorded.
+	Fixed string
+	// repair returns the repaired contents of the known broken file.
+	repair func(data []byte) ([]byte, error)
+}
+
+// Fixes lists the repairs of the known broken files of "diabdat.mpq".
+//
+// TODO: Record the SHA-1 hashes of the broken files of the retail
+// "diabdat.mpq", and of the repaired "unravw.cel", as logged by TestGameData.
+// Until recorded, the files are only checked by validating the repaired
+// contents.
+var Fixes = []Fix{
+	{
+		RelPath: "monsters/darkmage/dmagew.cl2",
+		// The repaired file does not depend on the broken file.
+		Fixed:  "0a43404a202524eb66b67fb4d3877a17e21419d5",
+		repair: dmagew,
+	},
+	{
+		RelPath: "monsters/unrav/unravw.cel",
+		repair:  unravw,
+	},
+}
+
+// Lookup returns the repair of the given file path, relative to
+// "diabdat.mpq". File paths are case-insensitive.
+func Lookup(relPath string) (Fix, bool) {
+	for _, fix := range Fixes {
+		if strings.EqualFold(fix.RelPath, relPath) {
+			return fix, true
+		}
+	}
+	return Fix{}, false
+}
+
+// Repair returns the repaired contents of the given file; or the contents
+// unchanged if the file is already repaired. An error is returned if the file
+// is an unknown variant, or fails to validate after repair.
+func (fix Fix) Repair(data []byte) ([]byte, error) {
+	// Check contents.
+	sum := sha1sum(data)
+	if fix.Fixed != "" && sum == fix.Fixed {
+		return data, nil
+	}
+	if validate(fix.RelPath, data) == nil {
+		// Already repaired.
+		return data, nil
+	}
+	if fix.Broken != "" && sum != fix.Broken {
+		return nil, errors.Errorf("unable to repair %q; unknown variant with SHA-1 hash %s", fix.RelPath, sum)
+	}
+
+	// Repair contents.
+	fixed, err := fix.repair(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Validate repaired contents.
+	if err := validate(fix.RelPath, fixed); err != nil {
+		return nil, errors.Wrapf(err, "unable to repair %q; variant with SHA-1 hash %s", fix.RelPath, sum)
+	}
+	if sum := sha1sum(fixed); fix.Fixed != "" && sum != fix.Fixed {
+		return nil, errors.Errorf("unable to repair %q; SHA-1 hash mismatch of repaired file; expected %s, got %s", fix.RelPath, fix.Fixed, sum)
+	}
+	return fixed, nil
+}
+
+// dmagew repairs "monsters/darkmage/dmagew.cl2", the walk animation of the dark
+// mage, which has an invalid archive header. The dark mage does not walk; each
+// of the eight directions contains 0 frames.
+func dmagew(data []byte) ([]byte, error) {
+	// Recreate archive of eight empty CL2 images.
+	//
+	//    celOffsets [8]uint32 // Offset to each embedded CL2 image.
+	//    imgs [8]struct {
+	//       nframes      uint32    // 0
+	//       frameOffsets [1]uint32 // End offset of image; 8
+	//    }
+	const nimgs = 8
+	fixed := make([]byte, 4*nimgs+8*nimgs)
+	for i := 0; i < nimgs; i++ {
+		binary.LittleEndian.PutUint32(fixed[4*i:], uint32(4*nimgs+8*i))
+		binary.LittleEndian.PutUint32(fixed[4*nimgs+8*i+4:], 8)
+	}
+	return fixed, nil
+}
+
+// unravwOffsets specifies the offsets of the embedded CEL images of
+// "monsters/unrav/unravw.cel".
+var unravwOffsets = [8]uint32{0x0020, 0x2707, 0x49C5, 0x6BA3, 0x86C3, 0xA926, 0xD04C, 0xED93}
+
+// unravw repairs "monsters/unrav/unravw.cel", the walk animation of the
+// unraveler, which has an invalid archive header. The offsets of the embedded
+// CEL images are restored.
+func unravw(data []byte) ([]byte, error) {
+	if len(data) <= int(unravwOffsets[len(unravwOffsets)-1]) {
+		return nil, errors.Errorf("unable to repair unravw.cel; file size %d too small", len(data))
+	}
+
+	// Restore archive header.
+	fixed := append([]byte(nil), data...)
+	for i, offset := range unravwOffsets {
+		binary.LittleEndian.PutUint32(fixed[4*i:], offset)
+	}
+	return fixed, nil
+}
+
+// validate checks the given contents of the CEL file against its image config.
+func validate(relPath string, data []byte) error {
+	mismatches, err := cel.VerifyData(relPath, data)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if len(mismatches) > 0 {
+		return errors.Errorf("invalid contents of %q; %v", relPath, mismatches[0])
+	}
+	return nil
+}
+
+// sha1sum returns the SHA-1 hash of the given data, in hexadecimal.
+func sha1sum(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
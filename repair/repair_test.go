@@ -0,0 +1,203 @@
+package repair
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sanctuary/formats/internal/diabdat"
+)
+
+func TestDmagew(t *testing.T) {
+	const relPath = "monsters/darkmage/dmagew.cl2"
+	broken := bytes.Repeat([]byte{0xFF}, 96)
+	fix, ok := Lookup(relPath)
+	if !ok {
+		t.Fatalf("unable to locate repair of %q", relPath)
+	}
+	// The SHA-1 hash of the broken file of "diabdat.mpq" is not yet recorded,
+	// thus broken files are repaired based on validation alone.
+	fixed, err := fix.Repair(broken)
+	if err != nil {
+		t.Fatalf("unable to repair CL2 archive; %+v", err)
+	}
+	if got := sha1sum(fixed); got != fix.Fixed {
+		t.Errorf("SHA-1 hash mismatch of repaired CL2 archive; expected %s, got %s", fix.Fixed, got)
+	}
+
+	// Repaired contents are returned unchanged.
+	if again, err := fix.Repair(fixed); err != nil || &again[0] != &fixed[0] {
+		t.Errorf("expected repaired contents to be returned unchanged; %v", err)
+	}
+
+	// Other contents are not repaired once the hash of the broken file is
+	// recorded.
+	fix.Broken = sha1sum(broken)
+	if _, err := fix.Repair(make([]byte, 96)); err == nil {
+		t.Errorf("expected error for unknown variant, got nil")
+	}
+}
+
+func TestUnravw(t *testing.T) {
+	const relPath = "monsters/unrav/unravw.cel"
+	valid := newUnravw(t)
+	broken := append([]byte(nil), valid...)
+	for i := 0; i < 4*len(unravwOffsets); i++ {
+		broken[i] = 0xFF
+	}
+	fix := Fix{RelPath: relPath, Broken: sha1sum(broken), Fixed: sha1sum(valid), repair: unravw}
+	fixed, err := fix.Repair(broken)
+	if err != nil {
+		t.Fatalf("unable to repair CEL archive; %+v", err)
+	}
+	if !bytes.Equal(fixed, valid) {
+		t.Errorf("repaired contents mismatch")
+	}
+
+	// Repaired contents are returned unchanged.
+	if again, err := fix.Repair(valid); err != nil || &again[0] != &valid[0] {
+		t.Errorf("expected repaired contents to be returned unchanged; %v", err)
+	}
+
+	// Contents repaired by other tools are returned unchanged, even if the
+	// hash of the repaired file is not recorded.
+	unknown := Fix{RelPath: relPath, repair: unravw}
+	if again, err := unknown.Repair(valid); err != nil || &again[0] != &valid[0] {
+		t.Errorf("expected valid contents to be returned unchanged; %v", err)
+	}
+
+	// Other contents are not repaired.
+	if _, err := fix.Repair(valid[:len(valid)-1]); err == nil {
+		t.Errorf("expected error for unknown variant, got nil")
+	}
+
+	// Known broken contents which fail to validate after repair.
+	invalid := append([]byte(nil), broken...)
+	invalid[len(invalid)-1] ^= 0xFF
+	fix = Fix{RelPath: relPath, Broken: sha1sum(invalid), repair: unravw}
+	if _, err := fix.Repair(invalid); err == nil {
+		t.Errorf("expected error for invalid repaired contents, got nil")
+	}
+	if _, err := unravw(make([]byte, 64)); err == nil {
+		t.Errorf("expected error for truncated CEL archive, got nil")
+	}
+}
+
+func TestGameData(t *testing.T) {
+	fsys := diabdat.FS(t)
+	for _, fix := range Fixes {
+		// Unmodified file of "diabdat.mpq".
+		data, err := fs.ReadFile(fsys, fix.RelPath)
+		if err != nil {
+			t.Errorf("unable to read %q; %+v", fix.RelPath, err)
+			continue
+		}
+		t.Logf("%q: SHA-1 hash %s", fix.RelPath, sha1sum(data))
+		fixed, err := fix.Repair(data)
+		if err != nil {
+			t.Errorf("unable to repair %q; %+v", fix.RelPath, err)
+			continue
+		}
+		t.Logf("%q: SHA-1 hash of repaired file %s", fix.RelPath, sha1sum(fixed))
+		got, err := fs.ReadFile(FS(fsys), fix.RelPath)
+		if err != nil {
+			t.Errorf("unable to read repaired %q; %+v", fix.RelPath, err)
+			continue
+		}
+		if !bytes.Equal(got, fixed) {
+			t.Errorf("%q: repaired contents mismatch", fix.RelPath)
+		}
+	}
+}
+
+func TestFS(t *testing.T) {
+	const relPath = "monsters/darkmage/dmagew.cl2"
+	broken := bytes.Repeat([]byte{0xFF}, 96)
+	defer func(fixes []Fix) { Fixes = fixes }(Fixes)
+	Fixes = []Fix{{RelPath: relPath, Broken: sha1sum(broken), repair: dmagew}}
+	other := []byte("other")
+	fsys := FS(fstest.MapFS{
+		relPath:                        {Data: broken},
+		"monsters/darkmage/dmagen.cl2": {Data: other},
+	})
+
+	// Known broken file.
+	got, err := fs.ReadFile(fsys, relPath)
+	if err != nil {
+		t.Fatalf("unable to read repaired file; %+v", err)
+	}
+	want, err := dmagew(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("repaired contents mismatch; expected %X, got %X", want, got)
+	}
+	fi, err := fs.Stat(fsys, relPath)
+	if err != nil {
+		t.Fatalf("unable to stat repaired file; %+v", err)
+	}
+	if fi.Size() != int64(len(want)) {
+		t.Errorf("file size mismatch; expected %d, got %d", len(want), fi.Size())
+	}
+
+	// Other files are read unchanged.
+	got, err = fs.ReadFile(fsys, "monsters/darkmage/dmagen.cl2")
+	if err != nil {
+		t.Fatalf("unable to read file; %+v", err)
+	}
+	if !bytes.Equal(got, other) {
+		t.Errorf("contents mismatch; expected %X, got %X", other, got)
+	}
+}
+
+// newUnravw returns a valid "monsters/unrav/unravw.cel" CEL archive, with the
+// embedded CEL images located at unravwOffsets. Each embedded CEL image
+// contains one 96x96 frame of type 1.
+func newUnravw(t *testing.T) []byte {
+	const (
+		frameHeader = 10
+		npixels     = 96 * 96
+	)
+	last := unravwOffsets[len(unravwOffsets)-1]
+	buf := make([]byte, last+unravwOffsets[1]-unravwOffsets[0])
+	for i, start := range unravwOffsets {
+		binary.LittleEndian.PutUint32(buf[4*i:], start)
+		end := uint32(len(buf))
+		if i+1 < len(unravwOffsets) {
+			end = unravwOffsets[i+1]
+		}
+		img := buf[start:end]
+		binary.LittleEndian.PutUint32(img[0:], 1)                // nframes
+		binary.LittleEndian.PutUint32(img[4:], 12)               // frameOffsets[0]
+		binary.LittleEndian.PutUint32(img[8:], uint32(len(img))) // frameOffsets[1]
+		frame := img[12:]
+		binary.LittleEndian.PutUint16(frame, frameHeader)
+		data := frame[frameHeader:]
+
+		// Fill the pixel data with nlit regular pixels of 2 bytes each (run
+		// length and colour), followed by the remaining transparent pixels
+		// split into ntrans runs of 1 byte each.
+		ntrans := 100
+		nlit := (len(data) - ntrans) / 2
+		ntrans = len(data) - 2*nlit
+		rem := npixels - nlit
+		if nlit < 0 || ntrans > rem || (rem+ntrans-1)/ntrans > 128 {
+			t.Fatalf("unable to encode %d pixels in %d bytes", npixels, len(data))
+		}
+		pos := 0
+		for j := 0; j < nlit; j++ {
+			data[pos], data[pos+1] = 1, 0x80
+			pos += 2
+		}
+		for j := 0; j < ntrans; j++ {
+			n := rem / (ntrans - j)
+			rem -= n
+			data[pos] = byte(-int8(n))
+			pos++
+		}
+	}
+	return buf
+}